@@ -0,0 +1,58 @@
+package normalizer
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// canonicalVersion identifies the rules producing the default output: the
+// format of numbers and strings, the key order and the handling of filler
+// symbols. It is bumped whenever a change of the rules changes the output of
+// any document, so that stored hashes can be told apart.
+const canonicalVersion = "2"
+
+// CanonicalVersion returns the version of the canonical form produced by this
+// release. Callers storing hashes or normalized documents can keep it along
+// with them, or pin it with WithCanonicalVersion.
+func CanonicalVersion() string {
+	return canonicalVersion
+}
+
+// Canonical serializes v using the default settings.
+func Canonical(v interface{}) ([]byte, error) {
+	return defaultNormalizer.Canonical(v)
+}
+
+// Canonical marshals v with encoding/json and normalizes the result, so that
+// equal values always produce the same bytes. encoding/json already sorts map
+// keys, but struct fields keep their declaration order, json.RawMessage and
+// Marshaler output is copied as is, and strings and numbers use its own
+// escaping and formatting; they all go through the normalizer here.
+func (n *Normalizer) Canonical(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return n.Normalize(data)
+}
+
+// CanonicalValue is like Canonical, but always uses the default settings and
+// verifies its result. The keys of every object are sorted at all levels of
+// nesting: maps, struct fields, including those of embedded and anonymous
+// structs, and the output of json.Marshaler and json.RawMessage values alike.
+// The output is checked to be a fixed point of Normalize, so that hashing or
+// storing it is safe; ErrNotIdempotent is returned otherwise.
+func CanonicalValue(v interface{}) ([]byte, error) {
+	data, err := defaultNormalizer.Canonical(v)
+	if err != nil {
+		return nil, err
+	}
+	again, err := defaultNormalizer.Normalize(data)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(data, again) {
+		return nil, ErrNotIdempotent
+	}
+	return data, nil
+}
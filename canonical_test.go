@@ -0,0 +1,199 @@
+package normalizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	check := func(v interface{}, expected string) {
+		data, err := Canonical(v)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	a := map[string]interface{}{}
+	a["b"] = 1
+	a["a"] = []interface{}{"x", map[string]interface{}{"d": true, "c": nil}}
+	a["c"] = json.RawMessage(`{"z": 1, "y": 2.50}`)
+
+	b := map[string]interface{}{}
+	b["c"] = json.RawMessage(`{"y":2.5,"z":1}`)
+	b["a"] = []interface{}{"x", map[string]interface{}{"c": nil, "d": true}}
+	b["b"] = 1.0
+
+	expected := `{"a":["x",{"c":null,"d":true}],"b":1,"c":{"y":2.5,"z":1}}`
+	check(a, expected)
+	check(b, expected)
+
+	type item struct {
+		Name  string  `json:"name"`
+		Count int     `json:"count"`
+		Price float64 `json:"price"`
+	}
+	check(item{Name: "<a&b>", Count: 2, Price: 1e22}, `{"count":2,"name":"<a&b>","price":1e+22}`)
+	check([]item{}, `[]`)
+	check(nil, `null`)
+
+	if _, err := Canonical(make(chan int)); err == nil {
+		t.Error("expected an error")
+	}
+
+	data, err := New(WithIndent("", "  ")).Canonical(map[string]int{"b": 1, "a": 2})
+	if err != nil {
+		t.Fatal(err)
+	} else if val := string(data); val != "{\n  \"a\": 2,\n  \"b\": 1\n}" {
+		t.Errorf("unexpected output: %s", val)
+	}
+}
+
+func TestCanonicalVersion(t *testing.T) {
+	if val := CanonicalVersion(); val != "2" {
+		t.Errorf("%v != 2", val)
+	}
+
+	check := func(n *Normalizer, expectedError error) {
+		if _, err := n.Normalize([]byte(`{"b": 1, "a": 2}`)); !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v", err, expectedError)
+		}
+	}
+
+	check(New(WithCanonicalVersion(CanonicalVersion())), nil)
+	check(New(WithCanonicalVersion("")), nil)
+	check(New(WithCanonicalVersion("0")), ErrUnsupportedVersion)
+	check(New(WithCanonicalVersion("1")), ErrUnsupportedVersion)
+	check(New(WithCanonicalVersion("3")), ErrUnsupportedVersion)
+
+	pinned := New(WithCanonicalVersion("3"))
+	if _, err := pinned.NormalizeStream(strings.NewReader(`1 2`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("%v != %v", err, ErrUnsupportedVersion)
+	}
+	if _, err := pinned.Hash([]byte(`1`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("%v != %v", err, ErrUnsupportedVersion)
+	}
+	if pinned.Valid([]byte(`1`)) {
+		t.Error("valid despite the version mismatch")
+	}
+}
+
+// unsortedMarshaler writes its keys out of order.
+type unsortedMarshaler struct{}
+
+func (unsortedMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"z": {"b": 1, "a": 2}, "a": [{"y": 1, "x": 2}]}`), nil
+}
+
+// keysSorted reports whether the keys of every object of the next value of
+// dec are in ascending order.
+func keysSorted(t *testing.T, dec *json.Decoder) bool {
+	tok, err := dec.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted := true
+	switch tok {
+	case json.Delim('{'):
+		for last := ""; dec.More(); {
+			key, err := dec.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key := key.(string); last != "" && key <= last {
+				sorted = false
+			} else {
+				last = key
+			}
+			sorted = keysSorted(t, dec) && sorted
+		}
+		dec.Token()
+	case json.Delim('['):
+		for dec.More() {
+			sorted = keysSorted(t, dec) && sorted
+		}
+		dec.Token()
+	}
+	return sorted
+}
+
+func TestCanonicalValue(t *testing.T) {
+	check := func(v interface{}, expected string) {
+		data, err := CanonicalValue(v)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+		if !keysSorted(t, json.NewDecoder(bytes.NewReader(data))) {
+			t.Errorf("keys not sorted: %s", data)
+		}
+		if again, err := Normalize(data); err != nil || !bytes.Equal(again, data) {
+			t.Errorf("not idempotent: %s != %s, %v", again, data, err)
+		}
+	}
+
+	type Base struct {
+		Zeta  int `json:"zeta"`
+		Alpha int `json:"alpha"`
+	}
+	type Doc struct {
+		Base
+		Name  string                 `json:"name"`
+		Attrs map[string]interface{} `json:"attrs"`
+		Inner struct {
+			Y     []struct{ Q, P int } `json:"y"`
+			X     *Base                `json:"x,omitempty"`
+			Extra json.RawMessage      `json:"extra"`
+		} `json:"inner"`
+		Custom unsortedMarshaler `json:"custom"`
+	}
+
+	var doc Doc
+	doc.Zeta, doc.Alpha = 1, 2
+	doc.Name = "doc"
+	doc.Attrs = map[string]interface{}{
+		"b": map[string]interface{}{"d": 1, "c": []interface{}{map[string]int{"f": 1, "e": 2}}},
+		"a": Base{Zeta: 3},
+	}
+	doc.Inner.Y = []struct{ Q, P int }{{Q: 1, P: 2}}
+	doc.Inner.X = &Base{}
+	doc.Inner.Extra = json.RawMessage(`{"n": 1.50, "m": {"l": true, "k": null}}`)
+
+	check(doc, `{"alpha":2,"attrs":{"a":{"alpha":0,"zeta":3},"b":{"c":[{"e":2,"f":1}],"d":1}},`+
+		`"custom":{"a":[{"x":2,"y":1}],"z":{"a":2,"b":1}},`+
+		`"inner":{"extra":{"m":{"k":null,"l":true},"n":1.5},"x":{"alpha":0,"zeta":0},"y":[{"P":2,"Q":1}]},`+
+		`"name":"doc","zeta":1}`)
+	check([]interface{}{map[string]Base{"y": {}, "x": {Zeta: 1}}, nil}, `[{"x":{"alpha":0,"zeta":1},"y":{"alpha":0,"zeta":0}},null]`)
+	check(struct{}{}, `{}`)
+	check("x", `"x"`)
+
+	// the result does not depend on the declaration order of the fields
+	type ab struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	type ba struct {
+		B int `json:"b"`
+		A int `json:"a"`
+	}
+	x, _ := CanonicalValue(ab{A: 1, B: 2})
+	y, _ := CanonicalValue(ba{A: 1, B: 2})
+	if !bytes.Equal(x, y) {
+		t.Errorf("%s != %s", x, y)
+	}
+
+	if keysSorted(t, json.NewDecoder(strings.NewReader(`[{"a": {"c": 1, "b": 2}}]`))) {
+		t.Error("unsorted keys not detected")
+	}
+
+	if _, err := CanonicalValue(make(chan int)); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,25 @@
+package normalizer
+
+import (
+	"bytes"
+)
+
+// Equal reports whether a and b are semantically equal JSON documents, i.e.
+// they only differ in key order, filler symbols or number and string
+// notation.
+func Equal(a, b []byte) (bool, error) {
+	return defaultNormalizer.Equal(a, b)
+}
+
+// Equal reports whether a and b normalize to the same document.
+func (n *Normalizer) Equal(a, b []byte) (bool, error) {
+	na, err := n.Normalize(a)
+	if err != nil {
+		return false, err
+	}
+	nb, err := n.Normalize(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(na, nb), nil
+}
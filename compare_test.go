@@ -0,0 +1,32 @@
+package normalizer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	check := func(a, b string, expected bool, expectedError error) {
+		val, err := Equal([]byte(a), []byte(b))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s, %s", err, expectedError, a, b)
+		} else if val != expected {
+			t.Errorf("%v != %v, src: %s, %s", val, expected, a, b)
+		}
+	}
+
+	check(`{"a":1,"b":2}`, `{"b":2,"a":1}`, true, nil)
+	check(`{"a":1,"b":2}`, ` { "b" : 2 , "a" : 1 } `, true, nil)
+	check(`[{"x": [1, 2]}]`, `[{"x":[1,2]}]`, true, nil)
+	check(`{"a":100}`, `{"a":1e2}`, true, nil)
+	check(`{"a":1.50}`, `{"a":15e-1}`, true, nil)
+	check(`-0`, `0`, true, nil)
+	check(`"\u0041"`, `"A"`, true, nil)
+
+	check(`{"a":1,"b":2}`, `{"a":1,"b":3}`, false, nil)
+	check(`[1, 2]`, `[2, 1]`, false, nil)
+	check(`{"a":1}`, `{"a":"1"}`, false, nil)
+
+	check(`{"a":1}`, `{"a":`, false, ErrUnexpectedEnd)
+	check(`{"a":x}`, `{"a":1}`, false, JsonSyntaxError)
+}
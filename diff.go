@@ -0,0 +1,192 @@
+package normalizer
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// ChangeType identifies the kind of a Change.
+type ChangeType int
+
+const (
+	Added ChangeType = iota
+	Removed
+	Changed
+)
+
+var changeTypeNames = [...]string{
+	Added:   "Added",
+	Removed: "Removed",
+	Changed: "Changed",
+}
+
+func (t ChangeType) String() string {
+	if t < 0 || int(t) >= len(changeTypeNames) {
+		return "ChangeType(?)"
+	}
+	return changeTypeNames[t]
+}
+
+// Change is a single difference between two documents. Path holds the object
+// keys and array indices leading to the value, Old and New its normalized
+// text, Old is nil for Added and New for Removed.
+type Change struct {
+	Type ChangeType
+	Path []string
+	Old  []byte
+	New  []byte
+}
+
+// Diff normalizes a and b and returns the differences between them. Objects
+// are compared member by member and arrays element by element, any other
+// difference is reported as a change of the whole value.
+func Diff(a, b []byte) ([]Change, error) {
+	return defaultNormalizer.Diff(a, b)
+}
+
+// Diff returns the differences between the normalized forms of a and b.
+func (n *Normalizer) Diff(a, b []byte) ([]Change, error) {
+	// the documents are compared in compact form, the values of the changes
+	// are laid out afterwards
+	c := n.compact()
+	na, err := c.Normalize(a)
+	if err != nil {
+		return nil, err
+	}
+	nb, err := c.Normalize(b)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := c.diff(nil, na, nb, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		if changes[i].Old != nil {
+			if changes[i].Old, err = n.relayout(changes[i].Old); err != nil {
+				return nil, err
+			}
+		}
+		if changes[i].New != nil {
+			if changes[i].New, err = n.relayout(changes[i].New); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+func (n *Normalizer) diff(path []string, a, b []byte, changes []Change) ([]Change, error) {
+	if bytes.Equal(a, b) {
+		return changes, nil
+	}
+	if a[0] != b[0] || a[0] != '{' && a[0] != '[' {
+		return append(changes, Change{Type: Changed, Path: path, Old: a, New: b}), nil
+	}
+
+	itemsA, err := n.children(a)
+	if err != nil {
+		return nil, err
+	}
+	itemsB, err := n.children(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// paths of the children share the parent, cap it so that they don't
+	// overwrite each other
+	path = path[:len(path):len(path)]
+
+	if a[0] == '[' {
+		for i := 0; i < len(itemsA) || i < len(itemsB); i++ {
+			switch {
+			case i >= len(itemsB):
+				changes = append(changes, Change{Type: Removed, Path: append(path, itemsA[i].name), Old: itemsA[i].value})
+			case i >= len(itemsA):
+				changes = append(changes, Change{Type: Added, Path: append(path, itemsB[i].name), New: itemsB[i].value})
+			default:
+				if changes, err = n.diff(append(path, itemsA[i].name), itemsA[i].value, itemsB[i].value, changes); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return changes, nil
+	}
+
+	indexB := make(map[string]int, len(itemsB))
+	for i := len(itemsB) - 1; i >= 0; i-- {
+		indexB[itemsB[i].name] = i
+	}
+	matched := make([]bool, len(itemsB))
+	for _, it := range itemsA {
+		if i, ok := indexB[it.name]; ok && !matched[i] {
+			matched[i] = true
+			if changes, err = n.diff(append(path, it.name), it.value, itemsB[i].value, changes); err != nil {
+				return nil, err
+			}
+		} else {
+			changes = append(changes, Change{Type: Removed, Path: append(path, it.name), Old: it.value})
+		}
+	}
+	for i, it := range itemsB {
+		if !matched[i] {
+			changes = append(changes, Change{Type: Added, Path: append(path, it.name), New: it.value})
+		}
+	}
+	return changes, nil
+}
+
+// child is a member or an element of a container.
+type child struct {
+	name  string
+	value []byte
+}
+
+// children splits the compact normalized object or array data into its
+// members or elements, elements are named by their index.
+func (n *Normalizer) children(data []byte) ([]child, error) {
+	p := n.newOutputParser(data)
+	start, err := p.readByte()
+	if err != nil {
+		return nil, p.truncated(err)
+	}
+	end := byte(']')
+	if start == '{' {
+		end = '}'
+	}
+
+	var items []child
+	if closed, err := p.parseClosing(end); err != nil {
+		return nil, err
+	} else if closed {
+		return items, nil
+	}
+
+	for {
+		name := strconv.Itoa(len(items))
+		if start == '{' {
+			if name, err = p.parseName(); err != nil {
+				return nil, p.truncated(err)
+			}
+		}
+		if val, err := p.sliceValue(data); err != nil {
+			return nil, err
+		} else {
+			items = append(items, child{name: name, value: val})
+		}
+
+		if err := p.skipFillers(); err != nil {
+			return nil, err
+		}
+		if c, err := p.readByte(); err != nil {
+			return nil, p.truncated(err)
+		} else if c == end {
+			return items, nil
+		} else if c != ',' {
+			return nil, p.syntaxError()
+		}
+		if err := p.skipFillers(); err != nil {
+			return nil, err
+		}
+	}
+}
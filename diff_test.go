@@ -0,0 +1,128 @@
+package normalizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	check := func(a, b string, expected []string, expectedError error) {
+		changes, err := Diff([]byte(a), []byte(b))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s, %s", err, expectedError, a, b)
+			return
+		}
+		val := make([]string, len(changes))
+		for i, c := range changes {
+			val[i] = fmt.Sprintf("%v %q %s -> %s", c.Type, c.Path, c.Old, c.New)
+		}
+		if fmt.Sprint(val) != fmt.Sprint(expected) {
+			t.Errorf("%q != %q, src: %s, %s", val, expected, a, b)
+		}
+	}
+
+	check(`{"a":1,"b":[1,2]}`, `{ "b": [1, 2.0], "a": 1e0 }`, []string{}, nil)
+
+	check(`{"a":{"b":{"c":1,"d":2}}}`, `{"a":{"b":{"c":1,"d":3}}}`, []string{
+		`Changed ["a" "b" "d"] 2 -> 3`,
+	}, nil)
+	check(`{"a":1,"b":2}`, `{"b":2,"c":3}`, []string{
+		`Removed ["a"] 1 -> `,
+		`Added ["c"]  -> 3`,
+	}, nil)
+	check(`{"a":{"x":1}}`, `{"a":[1]}`, []string{
+		`Changed ["a"] {"x":1} -> [1]`,
+	}, nil)
+
+	check(`[1,2,3]`, `[3,1,2]`, []string{
+		`Changed ["0"] 1 -> 3`,
+		`Changed ["1"] 2 -> 1`,
+		`Changed ["2"] 3 -> 2`,
+	}, nil)
+	check(`[1,[2,{"a":true}]]`, `[1,[2,{"a":false}],null]`, []string{
+		`Changed ["1" "1" "a"] true -> false`,
+		`Added ["2"]  -> null`,
+	}, nil)
+	check(`[1,2]`, `[1]`, []string{
+		`Removed ["1"] 2 -> `,
+	}, nil)
+	check(`"x"`, `"y"`, []string{
+		`Changed [] "x" -> "y"`,
+	}, nil)
+
+	check(`{"a":1}`, `{"a":x}`, nil, JsonSyntaxError)
+}
+
+func TestDiffPretty(t *testing.T) {
+	n := New(WithIndent("", "  "))
+	changes, err := n.Diff([]byte(`{"a":{"b":[1]}}`), []byte(`{"a":{"b":{"c":1}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("%d changes", len(changes))
+	}
+	if c := changes[0]; string(c.Old) != "[\n  1\n]" || string(c.New) != "{\n  \"c\": 1\n}" {
+		t.Errorf("%q -> %q", c.Old, c.New)
+	}
+
+	// the layout is not valid input, documents are compared in compact form
+	n = New(WithIndent("// ", "  "))
+	changes, err = n.Diff([]byte(`{"a":{"b":[1]},"c":2}`), []byte(`{"a":{"b":{"c":1}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("%d changes", len(changes))
+	}
+	if c := changes[0]; string(c.Old) != "[\n//   1\n// ]" || string(c.New) != "{\n//   \"c\": 1\n// }" {
+		t.Errorf("%q -> %q", c.Old, c.New)
+	}
+	if c := changes[1]; c.Type != Removed || string(c.Old) != `2` || c.New != nil {
+		t.Errorf("%v %q -> %q", c.Type, c.Old, c.New)
+	}
+}
+
+func TestDiffHooks(t *testing.T) {
+	check := func(n *Normalizer, a, b string, expected []string) {
+		changes, err := n.Diff([]byte(a), []byte(b))
+		if err != nil {
+			t.Errorf("%v, src: %s, %s", err, a, b)
+			return
+		}
+		val := make([]string, len(changes))
+		for i, c := range changes {
+			val[i] = fmt.Sprintf("%v %q %s -> %s", c.Type, c.Path, c.Old, c.New)
+		}
+		if fmt.Sprint(val) != fmt.Sprint(expected) {
+			t.Errorf("%q != %q, src: %s, %s", val, expected, a, b)
+		}
+	}
+
+	// the hooks apply once, not again to the normalized documents
+	keys := New(WithKeyTransformer(func(key string) (string, error) {
+		return strings.ToUpper(key) + "_", nil
+	}))
+	check(keys, `{"a":{"b":1}}`, `{"a":{"b":2}}`, []string{
+		`Changed ["A_" "B_"] 1 -> 2`,
+	})
+
+	strs := New(WithStringTransformer(func(decoded string) (string, error) {
+		return "x" + decoded, nil
+	}))
+	check(strs, `{"a":"b"}`, `{"a":"c"}`, []string{
+		`Changed ["a"] "xb" -> "xc"`,
+	})
+	check(strs, `[["b"]]`, `[["c"]]`, []string{
+		`Changed ["0" "0"] "xb" -> "xc"`,
+	})
+
+	nums := New(WithNumberFormatter(func(raw []byte) ([]byte, error) {
+		return append(raw[:len(raw):len(raw)], '0'), nil
+	}))
+	check(nums, `{"a":[1.5]}`, `{"a":[2.5]}`, []string{
+		`Changed ["a" "0"] 1.50 -> 2.50`,
+	})
+}
@@ -0,0 +1,246 @@
+package normalizer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+	"golang.org/x/text/transform"
+)
+
+// Encoding is the character encoding of the input, see WithInputEncoding.
+type Encoding int
+
+const (
+	// EncodingUTF8 reads the input as is. This is the default.
+	EncodingUTF8 Encoding = iota
+	// EncodingUTF16 reads UTF-16 in the byte order given by a leading byte
+	// order mark, big endian without one.
+	EncodingUTF16
+	// EncodingUTF16LE reads little endian UTF-16.
+	EncodingUTF16LE
+	// EncodingUTF16BE reads big endian UTF-16.
+	EncodingUTF16BE
+	// EncodingUTF32 reads UTF-32 in the byte order given by a leading byte
+	// order mark, big endian without one.
+	EncodingUTF32
+	// EncodingUTF32LE reads little endian UTF-32.
+	EncodingUTF32LE
+	// EncodingUTF32BE reads big endian UTF-32.
+	EncodingUTF32BE
+)
+
+// newInputParser returns a parser reading the input r, transcoded to UTF-8
+// when an input encoding is set.
+func (n *Normalizer) newInputParser(r reader) *parser {
+	if n.inputEncoding != EncodingUTF8 {
+		r = newTranscoder(r, n.inputEncoding)
+	}
+	return n.newParser(r)
+}
+
+// inputOffset returns the number of bytes of the input consumed by p, which
+// differs from its position when the input is transcoded.
+func (p *parser) inputOffset() int64 {
+	if t, ok := p.r.(*transcoder); ok {
+		return t.offset()
+	}
+	return p.pos.offset
+}
+
+// transcoder reads UTF-16 or UTF-32 input as UTF-8, one character at a time,
+// with the decoders of golang.org/x/text. A transform.Reader would read ahead
+// of the parser, losing the input offsets of NormalizeN and the exact reads of
+// ParseValue, and would silently replace invalid code units with U+FFFD.
+// Instead they are read as the invalid UTF-8 byte 0xFF, so that they are
+// handled like malformed UTF-8.
+type transcoder struct {
+	r      io.ByteReader
+	size   int  // bytes per code unit
+	little bool // little endian byte order
+	detect bool // the byte order is taken from a byte order mark
+
+	decoder     transform.Transformer
+	replacement []byte // U+FFFD in the input encoding
+
+	// in is the number of input bytes read
+	in  int64
+	eof bool
+
+	// src holds the input bytes read past the current character
+	srcBuf [8]byte
+	src    []byte
+
+	buf     [utf8.UTFMax]byte
+	seq     []byte // UTF-8 encoding of the current character
+	seqSize int    // input bytes of the current character
+	off     int    // read position in seq
+}
+
+func newTranscoder(r io.ByteReader, enc Encoding) *transcoder {
+	t := &transcoder{r: r, size: 2}
+	switch enc {
+	case EncodingUTF16:
+		t.detect = true
+	case EncodingUTF16LE:
+		t.little = true
+	case EncodingUTF32:
+		t.size, t.detect = 4, true
+	case EncodingUTF32LE:
+		t.size, t.little = 4, true
+	case EncodingUTF32BE:
+		t.size = 4
+	}
+	t.src = t.srcBuf[:0]
+	return t
+}
+
+// setDecoder creates the decoder once the byte order is known. Byte order
+// marks are decoded as characters, to be handled by WithStripBOM.
+func (t *transcoder) setDecoder() {
+	var enc encoding.Encoding
+	switch {
+	case t.size == 2 && t.little:
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case t.size == 2:
+		enc = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	case t.little:
+		enc = utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM)
+	default:
+		enc = utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM)
+	}
+	t.decoder = enc.NewDecoder()
+	t.replacement, _ = enc.NewEncoder().Bytes([]byte("\ufffd"))
+}
+
+func (t *transcoder) ReadByte() (byte, error) {
+	if t.off >= len(t.seq) {
+		if err := t.fill(); err != nil {
+			return 0, err
+		}
+	}
+	c := t.seq[t.off]
+	t.off++
+	return c, nil
+}
+
+func (t *transcoder) UnreadByte() error {
+	if t.off <= 0 {
+		return bufio.ErrInvalidUnreadByte
+	}
+	t.off--
+	return nil
+}
+
+func (t *transcoder) ReadRune() (rune, int, error) {
+	if t.off >= len(t.seq) {
+		if err := t.fill(); err != nil {
+			return 0, 0, err
+		}
+	}
+	ch, size := utf8.DecodeRune(t.seq[t.off:])
+	t.off += size
+	return ch, size, nil
+}
+
+// Read implements io.Reader for NormalizeLines, it returns early at the end
+// of a line so that lines are handled as soon as they are complete.
+func (t *transcoder) Read(b []byte) (int, error) {
+	for n := range b {
+		c, err := t.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		b[n] = c
+		if c == '\n' {
+			return n + 1, nil
+		}
+	}
+	return len(b), nil
+}
+
+// offset returns the number of input bytes up to the read position, which is
+// expected at the boundary of a character.
+func (t *transcoder) offset() int64 {
+	in := t.in - int64(len(t.src))
+	if t.off == 0 && len(t.seq) > 0 {
+		in -= int64(t.seqSize)
+	}
+	return in
+}
+
+// fill decodes the next character into seq.
+func (t *transcoder) fill() error {
+	for len(t.src) < t.size && !t.eof {
+		if err := t.read(); err != nil {
+			return err
+		}
+	}
+	if len(t.src) == 0 {
+		return io.EOF
+	}
+
+	if t.decoder == nil {
+		if t.detect {
+			// a little endian byte order mark, big endian is the default
+			t.little = len(t.src) == t.size && t.src[0] == 0xFF && t.src[1] == 0xFE &&
+				(t.size == 2 || t.src[2] == 0 && t.src[3] == 0)
+		}
+		t.setDecoder()
+	}
+
+	var n int
+	for {
+		var err error
+		n, _, err = t.decoder.Transform(t.buf[:], t.src, t.eof)
+		if n > 0 || err != transform.ErrShortSrc {
+			break
+		}
+		// a surrogate waiting for the next unit
+		if err := t.read(); err != nil {
+			return err
+		}
+	}
+
+	// only the first character is taken, the decoder may have gone further
+	ch, _ := utf8.DecodeRune(t.buf[:n])
+	units := 1
+	if ch == utf8.RuneError && !bytes.HasPrefix(t.src, t.replacement) {
+		t.buf[0] = 0xFF
+		t.seq = t.buf[:1]
+	} else {
+		t.seq = t.buf[:utf8.RuneLen(ch)]
+		if t.size == 2 && ch > 0xFFFF {
+			units = 2
+		}
+	}
+	t.seqSize = units * t.size
+	if t.seqSize > len(t.src) {
+		// a unit truncated by the end of the input
+		t.seqSize = len(t.src)
+	}
+	t.src = t.srcBuf[:copy(t.srcBuf[:], t.src[t.seqSize:])]
+	t.off = 0
+	return nil
+}
+
+// read appends the next code unit to src, it stops short at the end of the
+// input.
+func (t *transcoder) read() error {
+	for n := len(t.src) + t.size; len(t.src) < n; {
+		c, err := t.r.ReadByte()
+		if err == io.EOF {
+			t.eof = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		t.in++
+		t.src = append(t.src, c)
+	}
+	return nil
+}
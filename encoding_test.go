@@ -0,0 +1,125 @@
+package normalizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// encode returns s encoded with enc, invalid units can be given as
+// surrogates of units.
+func encode(enc Encoding, s string, units ...uint16) []byte {
+	var order binary.AppendByteOrder = binary.BigEndian
+	if enc == EncodingUTF16LE || enc == EncodingUTF32LE {
+		order = binary.LittleEndian
+	}
+
+	var buf []byte
+	if enc == EncodingUTF32LE || enc == EncodingUTF32BE || enc == EncodingUTF32 {
+		for _, ch := range s {
+			buf = order.AppendUint32(buf, uint32(ch))
+		}
+		for _, u := range units {
+			buf = order.AppendUint32(buf, uint32(u))
+		}
+		return buf
+	}
+	for _, u := range append(utf16.Encode([]rune(s)), units...) {
+		buf = order.AppendUint16(buf, u)
+	}
+	return buf
+}
+
+func TestWithInputEncoding(t *testing.T) {
+	check := func(n *Normalizer, src []byte, expected string, expectedError error) {
+		val, err := n.Normalize(src)
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: % x", err, expectedError, src)
+			return
+		}
+		if string(val) != expected {
+			t.Errorf("%s != %s, src: % x", val, expected, src)
+		}
+	}
+
+	src := "\ufeff{\"b\": [1, 2.50], \"a\": \"é\U0001f600\"}"
+	expected := "{\"a\":\"é\U0001f600\",\"b\":[1,2.5]}"
+	for _, enc := range []Encoding{EncodingUTF16LE, EncodingUTF16BE, EncodingUTF32LE, EncodingUTF32BE} {
+		n := New(WithInputEncoding(enc))
+		check(n, encode(enc, src), expected, nil)
+		check(n, encode(enc, src[3:]), expected, nil)
+		check(n, encode(enc, `{"a":`), ``, ErrUnexpectedEnd)
+	}
+
+	// the byte order is detected from the mark
+	utf16 := New(WithInputEncoding(EncodingUTF16))
+	check(utf16, encode(EncodingUTF16LE, src), expected, nil)
+	check(utf16, encode(EncodingUTF16BE, src), expected, nil)
+	check(utf16, encode(EncodingUTF16BE, src[3:]), expected, nil)
+	utf32 := New(WithInputEncoding(EncodingUTF32))
+	check(utf32, encode(EncodingUTF32LE, src), expected, nil)
+	check(utf32, encode(EncodingUTF32BE, src[3:]), expected, nil)
+
+	// the mark is subject to WithStripBOM
+	keepBOM := New(WithInputEncoding(EncodingUTF16LE), WithStripBOM(false))
+	check(keepBOM, encode(EncodingUTF16LE, src), ``, JsonSyntaxError)
+
+	// invalid units are malformed UTF-8
+	le := New(WithInputEncoding(EncodingUTF16LE))
+	check(le, encode(EncodingUTF16LE, `"a`, 0xd800, '"'), ``, ErrInvalidUTF8)
+	check(le, encode(EncodingUTF16LE, `"a`, 0xdc00, '"'), ``, ErrInvalidUTF8)
+	check(le, encode(EncodingUTF16LE, `"a`, 0xd800), ``, ErrInvalidUTF8)
+	check(le, append(encode(EncodingUTF16LE, `"a"`), ' '), ``, JsonSyntaxError)
+	check(New(WithInputEncoding(EncodingUTF32BE)), encode(EncodingUTF32BE, `"a`, 0xd800, '"'), ``, ErrInvalidUTF8)
+	check(New(WithInputEncoding(EncodingUTF32BE)), append(encode(EncodingUTF32BE, `"a`), 0, 0x11, 0, 0, 0, 0, 0, '"'), ``, ErrInvalidUTF8)
+	replace := New(WithInputEncoding(EncodingUTF16LE), WithReplaceInvalidUTF8(true))
+	check(replace, encode(EncodingUTF16LE, `"a`, 0xd800, '"'), "\"a�\"", nil)
+	check(replace, encode(EncodingUTF16LE, `"a`, 0xdc00, 0xdc00, 'b', '"'), "\"a��b\"", nil)
+	check(replace, encode(EncodingUTF16LE, `"a`, 0xd800, 0xd83d, 0xde00, '"'), "\"a�\U0001f600\"", nil)
+
+	// a replacement character in the input is valid
+	check(le, encode(EncodingUTF16LE, "\"a\ufffd\""), "\"a\ufffd\"", nil)
+	check(New(WithInputEncoding(EncodingUTF32LE)), encode(EncodingUTF32LE, "\"a\ufffd\""), "\"a\ufffd\"", nil)
+
+	// UTF-8 input is not valid UTF-16
+	check(le, []byte(`{"a":1}`), ``, JsonSyntaxError)
+}
+
+func TestWithInputEncodingReaders(t *testing.T) {
+	n := New(WithInputEncoding(EncodingUTF16LE))
+	src := encode(EncodingUTF16LE, "[1, 2] {\"a\": \"é\"} 3 ")
+
+	values, err := n.NormalizeStream(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3 || string(values[1]) != "{\"a\":\"é\"}" {
+		t.Errorf("unexpected values: %q", values)
+	}
+
+	// NormalizeN counts the bytes of the input
+	val, size, err := n.NormalizeN(src)
+	if err != nil || string(val) != `[1,2]` || size != 12 {
+		t.Errorf("%s, %d, %v", val, size, err)
+	}
+	val, size, err = n.NormalizeN(src[14:])
+	if err != nil || string(val) != "{\"a\":\"é\"}" || size != 20 {
+		t.Errorf("%s, %d, %v", val, size, err)
+	}
+	val, size, err = n.NormalizeN(src[36:])
+	if err != nil || string(val) != `3` || size != 2 {
+		t.Errorf("%s, %d, %v", val, size, err)
+	}
+
+	var sb strings.Builder
+	lines := encode(EncodingUTF16BE, "\ufeff{\"b\": 1, \"a\": 2}\n\n[3]\n")
+	if err := New(WithInputEncoding(EncodingUTF16)).NormalizeLines(bytes.NewReader(lines), &sb); err != nil {
+		t.Fatal(err)
+	}
+	if val := sb.String(); val != "{\"a\":2,\"b\":1}\n[3]\n" {
+		t.Errorf("unexpected output: %q", val)
+	}
+}
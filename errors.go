@@ -0,0 +1,197 @@
+package normalizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// JsonSyntaxError is matched by every error reporting malformed input.
+	JsonSyntaxError = errors.New("Syntax error")
+
+	// ErrDuplicateKey reports a repeated object key when duplicates are
+	// rejected, it is matched by DuplicateKeyError.
+	ErrDuplicateKey = errors.New("Duplicate key")
+
+	// ErrMaxDepthExceeded reports a document nested deeper than allowed.
+	ErrMaxDepthExceeded = errors.New("Max depth exceeded")
+
+	// ErrMaxNodesExceeded reports a document with more values and keys than
+	// allowed.
+	ErrMaxNodesExceeded = errors.New("Max nodes exceeded")
+
+	// ErrTokenTooLong reports a number or string longer than allowed.
+	ErrTokenTooLong = errors.New("Token too long")
+
+	// ErrOutputTooLarge reports a normalized document larger than allowed.
+	ErrOutputTooLarge = errors.New("Output too large")
+
+	// ErrInvalidUTF8 reports malformed UTF-8 in a string, it is returned as
+	// the cause of a SyntaxError.
+	ErrInvalidUTF8 = errors.New("Invalid UTF-8")
+
+	// ErrTrailingData reports content following the top-level value, it is
+	// returned as the cause of a SyntaxError.
+	ErrTrailingData = errors.New("Trailing data")
+
+	// ErrUnexpectedEnd reports input ending in the middle of a value, it is
+	// returned as the cause of a SyntaxError.
+	ErrUnexpectedEnd = errors.New("Unexpected end of input")
+
+	// ErrEmptyInput reports input without any value.
+	ErrEmptyInput = errors.New("Empty input")
+
+	// ErrTopLevelNotAllowed reports a top-level value of a kind left out of
+	// WithAllowedTopLevel, it is matched by TopLevelError.
+	ErrTopLevelNotAllowed = errors.New("Top-level value not allowed")
+
+	// ErrUnsupportedVersion reports a canonical version pinned with
+	// WithCanonicalVersion which differs from the one produced.
+	ErrUnsupportedVersion = errors.New("Unsupported canonical version")
+
+	// ErrNotIdempotent reports output of CanonicalValue which changes when
+	// normalized again.
+	ErrNotIdempotent = errors.New("Output not idempotent")
+
+	// ErrPathNotFound reports a path that does not lead to a value.
+	ErrPathNotFound = errors.New("Path not found")
+)
+
+// SyntaxError describes malformed input and the position where parsing
+// failed. It matches JsonSyntaxError with errors.Is, as well as Err when a
+// more specific cause is known.
+type SyntaxError struct {
+	Err    error // specific cause, may be nil
+	Offset int64 // byte offset of the offending input, starting at 0
+	Line   int   // line number, starting at 1
+	Column int   // column within the line in characters, starting at 1
+
+	// Path leads to the value being parsed, like a.b[2]["c d"]. It is empty
+	// at the top level.
+	Path string
+
+	// excerpt is the offending line, when the input is held in memory, and
+	// excerptAt the index of the error within it
+	excerpt   []byte
+	excerptAt int
+}
+
+func (e *SyntaxError) Error() string {
+	msg := JsonSyntaxError.Error()
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	msg = fmt.Sprintf("%s at line %d, column %d (offset %d)", msg, e.Line, e.Column, e.Offset)
+	if e.Path != "" {
+		msg += " in " + e.Path
+	}
+	return msg
+}
+
+func (e *SyntaxError) Is(target error) bool {
+	return target == JsonSyntaxError
+}
+
+// Context renders the line of the input containing the error with a caret
+// under the offending character, for human readers:
+//
+//	{"a": 1,, "b": 2}
+//	        ^
+//
+// Long lines are cut around the error, which is marked by "...". Context is
+// empty unless the input was given as a byte slice, input read from an
+// io.Reader is not kept.
+func (e *SyntaxError) Context() string {
+	if e.excerpt == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.Write(e.excerpt)
+	sb.WriteByte('\n')
+	// tabs are kept so that the caret lines up
+	for _, ch := range string(e.excerpt[:e.excerptAt]) {
+		if ch == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('^')
+	return sb.String()
+}
+
+// contextWidth is the number of bytes of the line shown on either side of a
+// syntax error by Context.
+const contextWidth = 40
+
+// excerpt returns a copy of the line of data containing offset, cut to
+// contextWidth bytes on either side, and the index of offset within it.
+func excerpt(data []byte, offset int) ([]byte, int) {
+	start := bytes.LastIndexAny(data[:offset], "\r\n") + 1
+	cutStart := start < offset-contextWidth
+	if cutStart {
+		start = offset - contextWidth
+		for start < offset && !utf8.RuneStart(data[start]) {
+			start++
+		}
+	}
+	end := len(data)
+	if i := bytes.IndexAny(data[offset:], "\r\n"); i >= 0 {
+		end = offset + i
+	}
+	cutEnd := end > offset+contextWidth
+	if cutEnd {
+		end = offset + contextWidth
+		for end > offset && !utf8.RuneStart(data[end]) {
+			end--
+		}
+	}
+
+	line := make([]byte, 0, end-start+6)
+	if cutStart {
+		line = append(line, "..."...)
+	}
+	at := len(line) + offset - start
+	line = append(line, data[start:end]...)
+	if cutEnd {
+		line = append(line, "..."...)
+	}
+	return line, at
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateKeyError reports the key repeated in an object when duplicates are
+// rejected. It matches ErrDuplicateKey with errors.Is.
+type DuplicateKeyError struct {
+	Key string // decoded key
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return ErrDuplicateKey.Error() + " " + strconv.Quote(e.Key)
+}
+
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// TopLevelError reports a top-level value of a kind left out of
+// WithAllowedTopLevel. It matches ErrTopLevelNotAllowed with errors.Is.
+type TopLevelError struct {
+	Kind Kind
+}
+
+func (e *TopLevelError) Error() string {
+	return "Top-level " + e.Kind.String() + " not allowed"
+}
+
+func (e *TopLevelError) Is(target error) bool {
+	return target == ErrTopLevelNotAllowed
+}
@@ -0,0 +1,199 @@
+package normalizer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	check := func(src string, offset int64, line, column int) {
+		_, err := Normalize([]byte(src))
+		if !errors.Is(err, JsonSyntaxError) {
+			t.Errorf("%v is not %v, src: %q", err, JsonSyntaxError, src)
+		}
+
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("%v is not a *SyntaxError, src: %q", err, src)
+		}
+		if serr.Offset != offset || serr.Line != line || serr.Column != column {
+			t.Errorf("%d:%d (offset %d) != %d:%d (offset %d), src: %q",
+				serr.Line, serr.Column, serr.Offset, line, column, offset, src)
+		}
+	}
+
+	check(`x`, 0, 1, 1)
+	check(`[1, x]`, 4, 1, 5)
+	check(`{"a": 1 "b": 2}`, 8, 1, 9)
+	check("{\n  \"a\": 1,\n  \"b\": tru\n}", 22, 3, 11)
+	check(`["é", y]`, 7, 1, 7)
+	check(`[1.2.3]`, 4, 1, 5)
+}
+
+func TestSyntaxErrorLineEndings(t *testing.T) {
+	check := func(src string, offset int64, line, column int) {
+		_, err := Normalize([]byte(src))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("%v is not a SyntaxError, src: %q", err, src)
+		} else if serr.Line != line || serr.Column != column || serr.Offset != offset {
+			t.Errorf("%d:%d (offset %d) != %d:%d (offset %d), src: %q",
+				serr.Line, serr.Column, serr.Offset, line, column, offset, src)
+		}
+	}
+
+	check("[\n1,\n  x]", 7, 3, 3)
+	check("[\r\n1,\r\n  x]", 9, 3, 3)
+	check("[\r1,\r  x]", 7, 3, 3)
+	check("[\r\n1,\r  x]", 8, 3, 3)
+	check("[\r1,\n  x]", 7, 3, 3)
+	check("[\n\r1,\n\r  x]", 9, 5, 3)
+	check("[\r\r\n\n1, x]", 8, 4, 4)
+	check("[1,\r\n\r\n", 7, 3, 1)
+}
+
+func TestSyntaxErrorMessage(t *testing.T) {
+	err := &SyntaxError{Offset: 10, Line: 2, Column: 3}
+	if val := err.Error(); val != "Syntax error at line 2, column 3 (offset 10)" {
+		t.Errorf("unexpected message: %s", val)
+	}
+	if !errors.Is(err, JsonSyntaxError) {
+		t.Errorf("%v is not %v", err, JsonSyntaxError)
+	}
+}
+
+func TestSyntaxErrorPath(t *testing.T) {
+	check := func(n *Normalizer, src, path string) {
+		_, err := n.Normalize([]byte(src))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("%v is not a SyntaxError, src: %s", err, src)
+		} else if serr.Path != path {
+			t.Errorf("%q != %q, src: %s", serr.Path, path, src)
+		}
+	}
+
+	check(New(), `x`, ``)
+	check(New(), `[1, x]`, `[1]`)
+	check(New(), `{"a": {"b": [1, 2, {"c": tru}]}}`, `a.b[2].c`)
+	check(New(), `{"a": {"b": [1, 2, {"c": 1 x}]}}`, `a.b[2]`)
+	check(New(), `{"a": {"b": [1, 2, {"c": 1}], "d": "\x"}}`, `a.d`)
+	check(New(), `[[], [[0, {"c d": [01]}]]]`, `[1][0][1]["c d"][0]`)
+	check(New(), `{"": {"1a": {"_b$2": nul}}}`, `[""]["1a"]._b$2`)
+	check(New(), `{"a": [1, `, `a[1]`)
+	check(New(), `{"a": 1} x`, ``)
+	check(New(WithSortKeys(false)), `{"b": 1, "a": [true, fals]}`, `a[1]`)
+	check(New(WithSortKeys(false)), `{"a": {"b c": [{"d": 1, "e": x}]}}`, `a["b c"][0].e`)
+
+	_, err := Normalize([]byte(`{"a": [1, 2, x]}`))
+	if val := err.Error(); val != "Syntax error at line 1, column 14 (offset 13) in a[2]" {
+		t.Errorf("unexpected message: %s", val)
+	}
+}
+
+func TestSyntaxErrorContext(t *testing.T) {
+	check := func(src, expected string) {
+		_, err := Normalize([]byte(src))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("%v is not a *SyntaxError, src: %q", err, src)
+		}
+		if val := serr.Context(); val != expected {
+			t.Errorf("%q != %q, src: %q", val, expected, src)
+		}
+	}
+
+	check(`{"a": 1,, "b": 2}`, `{"a": 1,, "b": 2}`+"\n"+`        ^`)
+	check(`x`, "x\n^")
+	check("{\n  \"a\": 1,\n  \"b\": tru\n}", "  \"b\": tru\n          ^")
+	check("[1,\r\n\t\"é\", y]", "\t\"é\", y]\n\t     ^")
+	check("[1,\n", "\n^")
+	check("{\"a\"\t1}", "{\"a\"\t1}\n    \t^")
+
+	long := strings.Repeat("1,", 50)
+	check("["+long+"x,"+long+"1]", "..."+long[:40]+"x,"+long[:38]+"...\n"+strings.Repeat(" ", 43)+"^")
+
+	_, err := New().NormalizeStream(strings.NewReader(`[1, x]`))
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Context() != "" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	check := func(n *Normalizer, src string, expected ...error) {
+		_, err := n.Normalize([]byte(src))
+		for _, target := range expected {
+			if !errors.Is(err, target) {
+				t.Errorf("%v is not %v, src: %q", err, target, src)
+			}
+		}
+	}
+
+	check(New(), `[1 2]`, JsonSyntaxError)
+	check(New(), `1 2`, JsonSyntaxError, ErrTrailingData)
+	check(New(), `{"a":1} {}`, JsonSyntaxError, ErrTrailingData)
+	check(New(), "\"\xff\"", JsonSyntaxError, ErrInvalidUTF8)
+	check(New(WithMaxDepth(1)), `[[1]]`, ErrMaxDepthExceeded)
+	check(New(WithDuplicateKeys(DuplicateKeysError)), `{"a":1,"a":2}`, ErrDuplicateKey)
+	check(New(), ``, ErrEmptyInput)
+
+	_, err := Normalize([]byte(`[1 2]`))
+	if errors.Is(err, ErrTrailingData) || errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("%v matches an unrelated cause", err)
+	}
+}
+
+func TestSyntaxErrorCauseMessage(t *testing.T) {
+	_, err := Normalize([]byte("[1]\n  2"))
+	if val := err.Error(); val != "Trailing data at line 2, column 3 (offset 6)" {
+		t.Errorf("unexpected message: %s", val)
+	}
+}
+
+func TestUnterminatedStringPosition(t *testing.T) {
+	_, err := Normalize([]byte("[\n\"abc"))
+
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("%v is not a *SyntaxError", err)
+	}
+	if serr.Offset != 6 || serr.Line != 2 || serr.Column != 5 {
+		t.Errorf("%d:%d (offset %d) != 2:5 (offset 6)", serr.Line, serr.Column, serr.Offset)
+	}
+}
+
+func TestUnterminatedContainers(t *testing.T) {
+	check := func(src string, offset int64) {
+		_, err := Normalize([]byte(src))
+		if !errors.Is(err, JsonSyntaxError) || !errors.Is(err, ErrUnexpectedEnd) {
+			t.Errorf("%v is not %v, src: %q", err, ErrUnexpectedEnd, src)
+		}
+
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("%v is not a *SyntaxError, src: %q", err, src)
+		}
+		if serr.Offset != offset {
+			t.Errorf("offset %d != %d, src: %q", serr.Offset, offset, src)
+		}
+	}
+
+	check(`[1`, 2)
+	check(`[1 `, 3)
+	check(`[1,`, 3)
+	check(`[1, `, 4)
+	check(`[`, 1)
+	check(`[[1, 2]`, 7)
+	check(`{"a":1`, 6)
+	check(`{"a":1 `, 7)
+	check(`{"a":1,`, 7)
+	check(`{"a":`, 5)
+	check(`{"a"`, 4)
+	check(`{"a`, 3)
+	check(`{`, 1)
+	check(`{"a":[-1.5e3`, 12)
+	check(`[tru`, 4)
+	check(`{"a":nul`, 8)
+}
@@ -0,0 +1,61 @@
+package normalizer
+
+import (
+	"bytes"
+	"testing"
+)
+
+var fuzzSeeds = []string{
+	``,
+	`null`,
+	`true`,
+	`false`,
+	`0`,
+	`-0.0`,
+	`123.456e-7`,
+	`1E400`,
+	`10000000000E10000000000`,
+	`1e2147483648`,
+	`00`,
+	`1.`,
+	`.5`,
+	`""`,
+	`"é😀"`,
+	`"\ud800"`,
+	`"a\"b\\c\/\b\f\n\r\t"`,
+	`"\x"`,
+	"\"\xff\"",
+	`[]`,
+	`[1, [2, [3]], {}]`,
+	`[1,]`,
+	`{"b": 1, "a": "x"}`,
+	`{"a": 1, "a": 2}`,
+	`{"a": {"c": [true, null], "b": -1.50}}`,
+	`{"a"}`,
+	`{"a":`,
+	"\xef\xbb\xbf{}",
+	` /* c */ [1 // c` + "\n]",
+}
+
+func FuzzNormalize(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		data, err := Normalize(src)
+		if errs := Validate(src); (len(errs) == 0) != (err == nil) || err != nil && errs[0].Error() != err.Error() {
+			t.Fatalf("%v, %v, src: %q", errs, err, src)
+		}
+		if err != nil {
+			return
+		}
+		again, err := Normalize(data)
+		if err != nil {
+			t.Fatalf("normalized output rejected: %v, src: %q, output: %q", err, src, data)
+		}
+		if !bytes.Equal(again, data) {
+			t.Fatalf("%q != %q, src: %q", again, data, src)
+		}
+	})
+}
@@ -0,0 +1,5 @@
+module github.com/alex-shch/json-normalizer
+
+go 1.18
+
+require golang.org/x/text v0.21.0
@@ -0,0 +1,20 @@
+package normalizer
+
+import (
+	"crypto/sha256"
+)
+
+// Hash returns the SHA-256 digest of the normalized form of src, so that
+// semantically equal documents hash identically.
+func Hash(src []byte) ([32]byte, error) {
+	return defaultNormalizer.Hash(src)
+}
+
+// Hash returns the SHA-256 digest of the normalized form of src.
+func (n *Normalizer) Hash(src []byte) ([32]byte, error) {
+	data, err := n.Normalize(src)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
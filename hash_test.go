@@ -0,0 +1,37 @@
+package normalizer
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	hash := func(src string) [32]byte {
+		h, err := Hash([]byte(src))
+		if err != nil {
+			t.Fatalf("%v, src: %s", err, src)
+		}
+		return h
+	}
+	check := func(a, b string, equal bool) {
+		if ha, hb := hash(a), hash(b); (ha == hb) != equal {
+			t.Errorf("hash equality %v != %v, src: %s, %s", ha == hb, equal, a, b)
+		}
+	}
+
+	check(`{"a":1,"b":[1,2]}`, `{"b": [1, 2], "a": 1}`, true)
+	check(`{"x":{"b":1,"a":2}}`, "{\n\t\"x\": {\"a\": 2, \"b\": 1}\n}", true)
+	check(`{"a":1.0}`, `{"a":1}`, true)
+
+	check(`{"a":1}`, `{"a":2}`, false)
+	check(`{"a":1}`, `{"b":1}`, false)
+	check(`[1,2]`, `[2,1]`, false)
+
+	if h := hash(`{"b":1,"a":2}`); h != sha256.Sum256([]byte(`{"a":2,"b":1}`)) {
+		t.Errorf("hash is not the digest of the normalized form")
+	}
+
+	if _, err := Hash([]byte(`{"a":`)); err == nil {
+		t.Errorf("expected an error for malformed input")
+	}
+}
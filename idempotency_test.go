@@ -0,0 +1,50 @@
+package normalizer
+
+import (
+	"bytes"
+	"testing"
+)
+
+var idempotencyCorpus = []string{
+	`0`, `-0`, `-0.0`, `0.000`, `1.0`, `100.00`, `1e2`, `1E+2`, `1e-2`, `-12.5e3`,
+	`0.1`, `0.0001`, `1e-7`, `123456789012345678901`, `1234567890123456789012`,
+	`1e21`, `1e400`, `-1e-400`, `4.9e-324`, `1.7976931348623157e308`, `12345678901234567890.123`,
+	`""`, `"abc"`, `"\"\\\/\b\f\n\r\t"`, `"\u0000\u001f\u007f"`, `"\u00e9\u00E9"`,
+	`"\ud83d\ude00"`, `"\u2028\u2029"`, `"<a&b>"`, `"\u003c\u003E\u0026"`, "\"\xf0\x9f\x98\x80\"",
+	`[]`, `{}`, `[[], {}, [{}]]`, `[1, "a", null, true, false]`,
+	`{"b": 1, "a": {"d": [3, 2, 1], "c": "x"}}`, `{"\u0061": 1, "b\n": 2, "": 3}`,
+	`{"a": 1, "A": 2, "_": 3, "é": 4, "😀": 5, "\uFB33": 6}`,
+}
+
+func TestIdempotent(t *testing.T) {
+	normalizers := map[string]*Normalizer{
+		"default":     New(),
+		"jcs":         New(WithJCS(true)),
+		"ascii":       New(WithASCIIOnly(true)),
+		"html":        New(WithEscapeHTML(true)),
+		"indent":      New(WithIndent("", "  ")),
+		"unsorted":    New(WithSortKeys(false)),
+		"utf16":       New(WithUTF16KeySort(true)),
+		"sortArrays":  New(WithSortArrays(true), WithDedupArrays(true)),
+		"insensitive": New(WithCaseInsensitiveSort(true)),
+	}
+
+	for name, n := range normalizers {
+		for _, src := range idempotencyCorpus {
+			first, err := n.Normalize([]byte(src))
+			if err != nil && n.jcs && src == `1e400` {
+				// out of range for the doubles of JCS
+				continue
+			} else if err != nil {
+				t.Errorf("%s: unexpected error: %v, src: %s", name, err, src)
+				continue
+			}
+			second, err := n.Normalize(first)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v, output: %s", name, err, first)
+			} else if !bytes.Equal(second, first) {
+				t.Errorf("%s: %s != %s, src: %s", name, second, first, src)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package normalizer
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// jcsNumber serializes a JSON number literal as required by RFC 8785: the
+// value is converted to an IEEE 754 double and written with the ECMAScript
+// Number.prototype.toString algorithm. Values outside of the double range are
+// rejected.
+func jcsNumber(src []byte) ([]byte, error) {
+	if _, err := canonicalNumber(src); err != nil {
+		return nil, err
+	}
+
+	f, err := strconv.ParseFloat(string(src), 64)
+	if err != nil {
+		return nil, JsonSyntaxError
+	}
+	if f == 0 {
+		return []byte("0"), nil
+	}
+
+	// the shortest representation which round-trips, as -d.ddde±x
+	buf := strconv.AppendFloat(make([]byte, 0, 32), f, 'e', -1, 64)
+
+	neg := buf[0] == '-'
+	if neg {
+		buf = buf[1:]
+	}
+
+	digits := make([]byte, 0, 17)
+	i := 0
+	for ; buf[i] != 'e'; i++ {
+		if buf[i] != '.' {
+			digits = append(digits, buf[i])
+		}
+	}
+	exp, _ := strconv.Atoi(string(buf[i+1:]))
+
+	return formatDecimal(neg, digits, exp-len(digits)+1), nil
+}
+
+// utf16Less orders strings by their UTF-16 code units, as RFC 8785 requires for
+// object keys. It only differs from the byte-wise order for characters outside
+// of the Basic Multilingual Plane, which sort before U+E000..U+FFFF.
+func utf16Less(a, b string) bool {
+	for len(a) > 0 && len(b) > 0 {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return utf16Key(ra) < utf16Key(rb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return len(a) < len(b)
+}
+
+// utf16Key maps a rune to a value whose order matches the order of the rune's
+// UTF-16 encoding, the code units are packed into the high and low halves.
+func utf16Key(r rune) uint32 {
+	if r < 0x10000 {
+		return uint32(r) << 16
+	}
+	r -= 0x10000
+	return uint32(0xd800+r>>10)<<16 | uint32(0xdc00+r&0x3ff)
+}
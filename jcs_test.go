@@ -0,0 +1,112 @@
+package normalizer
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestJCSNumbers(t *testing.T) {
+	n := New(WithJCS(true))
+	check := func(bits uint64, expected string) {
+		f := math.Float64frombits(bits)
+		for _, src := range []string{
+			strconv.FormatFloat(f, 'g', -1, 64),
+			strconv.FormatFloat(f, 'e', -1, 64),
+			strconv.FormatFloat(f, 'E', 20, 64),
+		} {
+			data, err := n.Normalize([]byte(src))
+			if err != nil {
+				t.Errorf("%v, src: %s", err, src)
+			} else if val := string(data); val != expected {
+				t.Errorf("%v != %v, src: %s", val, expected, src)
+			}
+		}
+	}
+
+	// RFC 8785, appendix B
+	check(0x0000000000000000, `0`)
+	check(0x8000000000000000, `0`)
+	check(0x0000000000000001, `5e-324`)
+	check(0x8000000000000001, `-5e-324`)
+	check(0x7fefffffffffffff, `1.7976931348623157e+308`)
+	check(0xffefffffffffffff, `-1.7976931348623157e+308`)
+	check(0x4340000000000000, `9007199254740992`)
+	check(0xc340000000000000, `-9007199254740992`)
+	check(0x4430000000000000, `295147905179352830000`)
+	check(0x44b52d02c7e14af5, `9.999999999999997e+22`)
+	check(0x44b52d02c7e14af6, `1e+23`)
+	check(0x44b52d02c7e14af7, `1.0000000000000001e+23`)
+	check(0x444b1ae4d6e2ef4e, `999999999999999700000`)
+	check(0x444b1ae4d6e2ef4f, `999999999999999900000`)
+	check(0x444b1ae4d6e2ef50, `1e+21`)
+	check(0x3eb0c6f7a0b5ed8c, `9.999999999999997e-7`)
+	check(0x3eb0c6f7a0b5ed8d, `0.000001`)
+	check(0x41b3de4355555553, `333333333.3333332`)
+	check(0x41b3de4355555554, `333333333.33333325`)
+	check(0x41b3de4355555555, `333333333.3333333`)
+	check(0x41b3de4355555556, `333333333.3333334`)
+	check(0x41b3de4355555557, `333333333.33333343`)
+	check(0xbecbf647612f3696, `-0.0000033333333333333333`)
+	check(0x43143ff3c1cb0959, `1424953923781206.2`)
+
+	if _, err := n.Normalize([]byte(`1e400`)); !errors.Is(err, JsonSyntaxError) {
+		t.Errorf("%v != %v", err, JsonSyntaxError)
+	}
+}
+
+func TestJCS(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithJCS(true))
+
+	// RFC 8785, section 3.2.2
+	check(n, `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, nil)
+
+	// RFC 8785, section 3.2.3
+	check(n, `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\","+
+		"\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}", nil)
+
+	check(n, `{"a": 1, "a": 2}`, ``, ErrDuplicateKey)
+	check(New(WithJCS(true), WithSortKeys(false), WithIndent("", "  ")), `{"b": [1], "a": 2}`, `{"a":2,"b":[1]}`, nil)
+}
+
+func TestUTF16Less(t *testing.T) {
+	check := func(a, b string, expected bool) {
+		if val := utf16Less(a, b); val != expected {
+			t.Errorf("utf16Less(%q, %q) %v != %v", a, b, val, expected)
+		}
+	}
+
+	check("a", "b", true)
+	check("b", "a", false)
+	check("a", "a", false)
+	check("a", "ab", true)
+	check("ab", "a", false)
+	check("", "a", true)
+	check("😀", "\ufb33", true)
+	check("\ufb33", "😀", false)
+	check("\ud7ff", "😀", true)
+	check("😀", "😁", true)
+	check("€", "😀", true)
+}
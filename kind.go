@@ -0,0 +1,110 @@
+package normalizer
+
+import (
+	"io"
+)
+
+// Kind identifies the type of a JSON value.
+type Kind int
+
+const (
+	KindObject Kind = iota
+	KindArray
+	KindString
+	KindNumber
+	KindBool
+	KindNull
+)
+
+var kindNames = [...]string{
+	KindObject: "object",
+	KindArray:  "array",
+	KindString: "string",
+	KindNumber: "number",
+	KindBool:   "bool",
+	KindNull:   "null",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "Kind(?)"
+	}
+	return kindNames[k]
+}
+
+// kindOf returns the kind of the value starting with c, which is meaningful
+// only when c can start a value.
+func kindOf(c byte) (Kind, bool) {
+	switch c {
+	case '{':
+		return KindObject, true
+	case '[':
+		return KindArray, true
+	case '"', '\'':
+		return KindString, true
+	case 't', 'f', 'T', 'F':
+		return KindBool, true
+	case 'n':
+		return KindNull, true
+	case '-', 'N', 'I':
+		return KindNumber, true
+	}
+	if isDigit(c) {
+		return KindNumber, true
+	}
+	return 0, false
+}
+
+// KindOf returns the kind of the top-level value of src using the default
+// settings.
+func KindOf(src []byte) (Kind, error) {
+	return defaultNormalizer.KindOf(src)
+}
+
+// KindOf returns the kind of the top-level value of src from its first
+// significant byte, skipping a byte order mark and filler symbols. The rest
+// of the document is not read, so src may still be malformed past that byte.
+func (n *Normalizer) KindOf(src []byte) (Kind, error) {
+	p := n.newInputParser(newSliceReader(src))
+	if err := p.skipBOM(); err != nil {
+		return 0, err
+	}
+	if err := p.skipFillers(); err != nil {
+		return 0, err
+	}
+
+	c, err := p.readByte()
+	if err == io.EOF {
+		return 0, ErrEmptyInput
+	} else if err != nil {
+		return 0, err
+	}
+
+	kind, ok, err := p.kindAt(c)
+	if err != nil {
+		return 0, err
+	}
+	if !ok || c == '\'' && !p.singleQuotes || (c == 'T' || c == 'F') && !p.caseInsensitiveLit ||
+		kind == KindNumber && (c == 'N' || c == 'I') && !p.allowNonFinite {
+		return 0, p.syntaxError()
+	}
+	return kind, nil
+}
+
+// kindAt is like kindOf for a value starting with c, which has just been
+// read. With case-insensitive literals a value starting with N is null
+// unless it is followed by the a of NaN and non-finite numbers are allowed.
+func (p *parser) kindAt(c byte) (Kind, bool, error) {
+	kind, ok := kindOf(c)
+	if c == 'N' && p.caseInsensitiveLit {
+		next, err := p.readByte()
+		if err != nil {
+			return 0, false, p.truncated(err)
+		}
+		p.unreadByte()
+		if next != 'a' || !p.allowNonFinite {
+			kind = KindNull
+		}
+	}
+	return kind, ok, nil
+}
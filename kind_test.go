@@ -0,0 +1,64 @@
+package normalizer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKindString(t *testing.T) {
+	check := func(kind Kind, expected string) {
+		if val := kind.String(); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(KindObject, "object")
+	check(KindArray, "array")
+	check(KindString, "string")
+	check(KindNumber, "number")
+	check(KindBool, "bool")
+	check(KindNull, "null")
+	check(Kind(-1), "Kind(?)")
+	check(Kind(42), "Kind(?)")
+}
+
+func TestKindOf(t *testing.T) {
+	check := func(n *Normalizer, src string, expected Kind, expectedError error) {
+		kind, err := n.KindOf([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if err == nil && kind != expected {
+			t.Errorf("%v != %v, src: %s", kind, expected, src)
+		}
+	}
+
+	n := New()
+	check(n, `{"a": 1}`, KindObject, nil)
+	check(n, ` [1, 2]`, KindArray, nil)
+	check(n, `"x"`, KindString, nil)
+	check(n, `-1.5`, KindNumber, nil)
+	check(n, `0`, KindNumber, nil)
+	check(n, `true`, KindBool, nil)
+	check(n, `false`, KindBool, nil)
+	check(n, "\xef\xbb\xbf\n\tnull", KindNull, nil)
+
+	// only the first byte is looked at
+	check(n, `[1, x`, KindArray, nil)
+
+	check(n, ``, 0, ErrEmptyInput)
+	check(n, "  \n", 0, ErrEmptyInput)
+	check(n, `x`, 0, JsonSyntaxError)
+	check(n, `}`, 0, JsonSyntaxError)
+	check(n, `'a'`, 0, JsonSyntaxError)
+	check(n, `NaN`, 0, JsonSyntaxError)
+	check(n, `/* c */ 1`, 0, JsonSyntaxError)
+
+	lenient := New(WithComments(true), WithSingleQuotes(true), WithAllowNonFiniteNumbers(true))
+	check(lenient, `'a'`, KindString, nil)
+	check(lenient, `NaN`, KindNumber, nil)
+	check(lenient, `/* c */ 1`, KindNumber, nil)
+
+	if kind, err := KindOf([]byte(`{}`)); err != nil || kind != KindObject {
+		t.Errorf("%v, %v", kind, err)
+	}
+}
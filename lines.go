@@ -0,0 +1,76 @@
+package normalizer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// NormalizeLines normalizes newline-delimited JSON (NDJSON, JSON Lines) using
+// the default settings.
+func NormalizeLines(r io.Reader, w io.Writer) error {
+	return defaultNormalizer.NormalizeLines(r, w)
+}
+
+// NormalizeLines reads one JSON value per line from r and writes each of them
+// normalized to w, followed by a newline. Blank lines are skipped unless
+// WithSkipBlankLines(false) is given, in which case they are reported as a
+// syntax error. Positions of syntax errors refer to the whole input. Each
+// line has to hold a single value, which is written compact on a line of its
+// own, so WithAllowMultipleValues, WithIndent and WithTopLevelArrayPerLine
+// are ignored.
+func (n *Normalizer) NormalizeLines(r io.Reader, w io.Writer) error {
+	single := *n
+	single.multipleValues = false
+	single.pretty = false
+	single.topLevelArrayPerLine = false
+
+	var br *bufio.Reader
+	if n.inputEncoding != EncodingUTF8 {
+		// lines are split after transcoding, the terminating \n is a
+		// different byte sequence before
+		br = bufio.NewReader(newTranscoder(bufio.NewReader(r), n.inputEncoding))
+	} else {
+		br = bufio.NewReader(r)
+	}
+	bw := bufio.NewWriter(w)
+
+	var offset int64
+	for line := 1; ; line++ {
+		buf, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(buf) == 0 && err == io.EOF {
+			break
+		}
+
+		if len(bytes.TrimLeft(buf, " \t\r\n")) == 0 {
+			if !n.skipBlankLines {
+				return &SyntaxError{Offset: offset, Line: line, Column: 1}
+			}
+		} else {
+			data, perr := single.newParser(newSliceReader(buf)).parseDocument()
+			if perr != nil {
+				var serr *SyntaxError
+				if errors.As(perr, &serr) {
+					serr.Offset += offset
+					serr.Line += line - 1
+				}
+				return perr
+			}
+			bw.Write(data)
+			if !n.trailingNewline {
+				bw.WriteByte('\n')
+			}
+		}
+
+		offset += int64(len(buf))
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,60 @@
+package normalizer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeLines(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		var w bytes.Buffer
+		err := n.NormalizeLines(strings.NewReader(src), &w)
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %q", err, expectedError, src)
+		} else if err == nil && w.String() != expected {
+			t.Errorf("%q != %q", w.String(), expected)
+		}
+	}
+
+	fixture := `{"b": 1, "a": {"d": true, "c": null}}
+[3, 2, 1]
+{"x": [ {"z": 1, "y": 2} ]}
+`
+	expected := `{"a":{"c":null,"d":true},"b":1}
+[3,2,1]
+{"x":[{"y":2,"z":1}]}
+`
+
+	check(New(), fixture, expected, nil)
+	check(New(), strings.TrimSuffix(fixture, "\n"), expected, nil)
+	check(New(), strings.Replace(fixture, "\n", "\r\n", -1), expected, nil)
+	check(New(), "\n"+strings.Replace(fixture, "\n", "\n  \n", 1), expected, nil)
+	check(New(), ``, ``, nil)
+
+	check(New(WithSkipBlankLines(false)), fixture, expected, nil)
+	check(New(WithSkipBlankLines(false)), strings.Replace(fixture, "\n", "\n\n", 1), ``, JsonSyntaxError)
+
+	check(New(), "1\n2 3\n", ``, JsonSyntaxError)
+	check(New(), "[1,\n2]\n", ``, ErrUnexpectedEnd)
+
+	// one value per line, whatever the layout options say
+	check(New(WithAllowMultipleValues(true)), "1\n2 3\n", ``, JsonSyntaxError)
+	check(New(WithAllowMultipleValues(true)), fixture, expected, nil)
+	check(New(WithIndent("", "  ")), fixture, expected, nil)
+	check(New(WithTopLevelArrayPerLine(true)), fixture, expected, nil)
+}
+
+func TestNormalizeLinesErrorPosition(t *testing.T) {
+	var w bytes.Buffer
+	err := NormalizeLines(strings.NewReader("1\n\n[2, x]\n"), &w)
+
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("%v is not a *SyntaxError", err)
+	}
+	if serr.Line != 3 || serr.Column != 5 || serr.Offset != 7 {
+		t.Errorf("%d:%d (offset %d) != 3:5 (offset 7)", serr.Line, serr.Column, serr.Offset)
+	}
+}
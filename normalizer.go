@@ -1,322 +1,1747 @@
 package normalizer
 
 import (
+	"bufio"
 	"bytes"
-	"errors"
+	"context"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-var JsonSyntaxError = errors.New("Syntax error")
+// DefaultMaxDepth is the nesting limit used unless WithMaxDepth is given.
+const DefaultMaxDepth = 10000
+
+// Normalizer holds the settings used to normalize JSON documents. It is
+// created with New and configured through Option values.
+//
+// A Normalizer is never modified after New returns and keeps no state between
+// calls: every call uses its own parser, and scratch buffers come from pools
+// shared by the package. A single Normalizer can therefore be reused for any
+// number of calls, also from multiple goroutines at once, without a reset.
+type Normalizer struct {
+	jcs                 bool
+	sortKeys            bool
+	caseInsensitiveSort bool
+	sortDescending      bool
+	utf16KeySort        bool
+	naturalKeySort      bool
+	keyComparator       func(a, b string) bool
+	keyNFC              bool
+	keyTransformer      func(key string) (string, error)
+	stringTransformer   func(decoded string) (string, error)
+	numberFormatter     func(raw []byte) ([]byte, error)
+	duplicateKeys       DuplicateKeyPolicy
+	maxDepth            int
+
+	maxNumberLength int
+	maxStringLength int
+	maxOutputSize   int
+	maxTotalNodes   int
+
+	replaceInvalidUTF8 bool
+	valueNFC           bool
+	trimStringValues   bool
+	recurseEmbedded    bool
+	asciiOnly          bool
+	escapeHTML         bool
+	comments           bool
+	singleQuotes       bool
+	unquotedKeys       bool
+	allowNonFinite     bool
+	caseInsensitiveLit bool
+	nonFiniteAsNull    bool
+	trailingCommas     bool
+	unicodeWhitespace  bool
+
+	skipBlankLines bool
+	stripBOM       bool
+
+	trailingNewline bool
+	multipleValues  bool
+	allowedTopLevel uint // bit set of Kind, all are allowed when empty
+
+	version string // pinned canonical version, if any
+
+	inputEncoding Encoding
+
+	omitNulls bool
+	omitEmpty bool
+
+	sortArrays  bool
+	dedupArrays bool
+
+	pretty bool
+	prefix string
+	indent string
+
+	topLevelArrayPerLine bool
+
+	separators    bool
+	itemSeparator string
+	keySeparator  string
+}
+
+// Option configures a Normalizer.
+type Option func(*Normalizer)
+
+// New returns a Normalizer configured with the given options.
+func New(opts ...Option) *Normalizer {
+	n := &Normalizer{
+		sortKeys:       true,
+		maxDepth:       DefaultMaxDepth,
+		skipBlankLines: true,
+		stripBOM:       true,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+var defaultNormalizer = New()
 
+// Normalize normalizes src using the default settings. Like every package
+// level function it is safe for concurrent use.
 func Normalize(src []byte) ([]byte, error) {
-	r := bytes.NewReader(src)
-	return parseValue(r)
+	return defaultNormalizer.Normalize(src)
+}
+
+// Normalize "sorts" the JSON document src and removes filler symbols.
+func (n *Normalizer) Normalize(src []byte) ([]byte, error) {
+	return n.newInputParser(newSliceReader(src)).parseDocument()
+}
+
+// NormalizeN normalizes the value at the start of src using the default
+// settings and returns the number of bytes it occupies.
+func NormalizeN(src []byte) ([]byte, int, error) {
+	return defaultNormalizer.NormalizeN(src)
+}
+
+// NormalizeN normalizes the first value of src, which may be followed by any
+// data, and returns the number of bytes consumed: a byte order mark and the
+// filler symbols in front of the value, and the value itself, but nothing
+// after it. src[n:] therefore starts right after the value. The exception is
+// a top-level number: its end is only known from the byte after it, which has
+// to be a filler symbol, ',', ']', '}' or the end of src, so `123x` is a
+// syntax error. On error n is 0.
+func (n *Normalizer) NormalizeN(src []byte) ([]byte, int, error) {
+	p := n.newInputParser(newSliceReader(src))
+	data, err := p.parseFirstValue(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, int(p.inputOffset()), nil
+}
+
+// NormalizePartial normalizes src using the default settings and returns the
+// partial output on error.
+func NormalizePartial(src []byte) ([]byte, error) {
+	return defaultNormalizer.NormalizePartial(src)
+}
+
+// NormalizePartial is like Normalize, but when src is malformed it returns
+// the output written before the error along with it, to show how far
+// normalization got. The members of unfinished objects are in input order,
+// they are only sorted once the object is closed. The partial output is not
+// valid JSON and empty when the error is met outside of any container.
+func (n *Normalizer) NormalizePartial(src []byte) ([]byte, error) {
+	p := n.newInputParser(newSliceReader(src))
+	p.keepPartial = true
+	data, err := p.parseDocument()
+	if err != nil {
+		return p.partial, err
+	}
+	return data, nil
+}
+
+// contextCheckInterval is the number of values parsed between two checks of
+// the context passed to NormalizeContext.
+const contextCheckInterval = 1024
+
+// NormalizeContext normalizes src using the default settings, aborting with
+// the context error once ctx is done.
+func NormalizeContext(ctx context.Context, src []byte) ([]byte, error) {
+	return defaultNormalizer.NormalizeContext(ctx, src)
+}
+
+// NormalizeContext is like Normalize but periodically checks ctx and aborts
+// with its error once it is done.
+func (n *Normalizer) NormalizeContext(ctx context.Context, src []byte) ([]byte, error) {
+	p := n.newInputParser(newSliceReader(src))
+	p.ctx = ctx
+	return p.parseDocument()
 }
 
-func skipFillers(r *bytes.Reader) error {
+// Valid reports whether src is a valid JSON document under the default
+// settings.
+func Valid(src []byte) bool {
+	return defaultNormalizer.Valid(src)
+}
+
+// Valid reports whether src would be normalized without an error. The output
+// is not built, so it is cheaper than calling Normalize.
+func (n *Normalizer) Valid(src []byte) bool {
+	p := n.newInputParser(newSliceReader(src))
+	p.discard = true
+	_, err := p.parseDocument()
+	return err == nil
+}
+
+// NormalizeString normalizes src using the default settings.
+func NormalizeString(src string) (string, error) {
+	return defaultNormalizer.NormalizeString(src)
+}
+
+// NormalizeString is like Normalize but works on strings. The input is read in
+// place rather than copied into a byte slice first.
+func (n *Normalizer) NormalizeString(src string) (string, error) {
+	data, err := n.newInputParser(strings.NewReader(src)).parseDocument()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// NormalizeTo normalizes src using the default settings and writes the result
+// to w.
+func NormalizeTo(w io.Writer, src []byte) (int, error) {
+	return defaultNormalizer.NormalizeTo(w, src)
+}
+
+// NormalizeTo normalizes src and writes the result to w, returning the number
+// of bytes written. Nothing is written if src is malformed.
+func (n *Normalizer) NormalizeTo(w io.Writer, src []byte) (int, error) {
+	data, err := n.Normalize(src)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(data)
+}
+
+// NormalizeReader normalizes the JSON document read from r using the default
+// settings and writes the result to w.
+func NormalizeReader(r io.Reader, w io.Writer) error {
+	return defaultNormalizer.NormalizeReader(r, w)
+}
+
+// NormalizeReader normalizes the JSON document read from r and writes the
+// result to w.
+//
+// The input is consumed incrementally through a buffered reader, so the
+// source document is never held in memory as a whole. The normalized form of
+// the top-level value is however assembled in memory before it is written:
+// keys of an object can only be emitted once the whole object has been read.
+func (n *Normalizer) NormalizeReader(r io.Reader, w io.Writer) error {
+	br, ok := r.(reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	data, err := n.newInputParser(br).parseDocument()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// reader is the part of *bytes.Reader, *strings.Reader and *bufio.Reader used
+// by the parser.
+type reader interface {
+	io.ByteScanner
+	io.RuneReader
+}
+
+// sliceReader is the reader used for documents held in memory, it lets the
+// parser scan runs of plain string content in place.
+type sliceReader struct {
+	data []byte
+	off  int
+}
+
+func newSliceReader(data []byte) *sliceReader {
+	return &sliceReader{data: data}
+}
+
+func (r *sliceReader) ReadByte() (byte, error) {
+	if r.off >= len(r.data) {
+		return 0, io.EOF
+	}
+	c := r.data[r.off]
+	r.off++
+	return c, nil
+}
+
+func (r *sliceReader) UnreadByte() error {
+	if r.off <= 0 {
+		return bufio.ErrInvalidUnreadByte
+	}
+	r.off--
+	return nil
+}
+
+func (r *sliceReader) ReadRune() (rune, int, error) {
+	if r.off >= len(r.data) {
+		return 0, 0, io.EOF
+	}
+	if c := r.data[r.off]; c < utf8.RuneSelf {
+		r.off++
+		return rune(c), 1, nil
+	}
+	ch, size := utf8.DecodeRune(r.data[r.off:])
+	r.off += size
+	return ch, size, nil
+}
+
+// parser holds the state of a single normalization run.
+type parser struct {
+	*Normalizer
+	r     reader
+	depth int
+
+	// discard skips building the output of strings and containers
+	discard bool
+
+	// ctx is checked every contextCheckInterval values when set
+	ctx    context.Context
+	values int
+
+	// nodes counts the values and keys parsed when their number is limited
+	nodes int
+
+	// partial keeps the output of the innermost unfinished container when
+	// parsing fails, if keepPartial is set
+	keepPartial bool
+	partial     []byte
+
+	// pos is the position of the next byte to read, prev the position of the
+	// last byte read
+	pos  position
+	prev position
+
+	// path leads to the value being parsed, it is reported with syntax
+	// errors
+	path []pathSegment
+
+	// collect records errors in errs and recovers from them, see Validate
+	collect bool
+	errs    []error
+
+	// inString is the quote of the string being decoded, if any
+	inString byte
+}
+
+type position struct {
+	offset int64
+	line   int
+	column int
+	cr     bool // the last byte read was a carriage return
+}
+
+// pathSegment is an object key, or an array index when index is not -1. The
+// key is held in name, or in key while the members of an object are streamed.
+type pathSegment struct {
+	name  string
+	key   []byte
+	index int
+}
+
+// pathString formats the path leading to the value being parsed like
+// a.b[2]["c d"], keys which are not identifiers are quoted.
+func (p *parser) pathString() string {
+	var sb strings.Builder
+	for _, seg := range p.path {
+		if seg.key != nil {
+			seg.name = string(seg.key)
+		}
+		if seg.index >= 0 {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(seg.index))
+			sb.WriteByte(']')
+		} else if isIdentifier(seg.name) {
+			if sb.Len() != 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(seg.name)
+		} else {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Quote(seg.name))
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String()
+}
+
+func (n *Normalizer) newParser(r reader) *parser {
+	return &parser{Normalizer: n, r: r, pos: position{line: 1, column: 1}}
+}
+
+// compact returns a copy of n writing compact output, which unlike the
+// layout set by WithIndent or WithSeparators is always valid input.
+func (n *Normalizer) compact() *Normalizer {
+	c := *n
+	c.pretty = false
+	c.separators = false
+	c.topLevelArrayPerLine = false
+	c.trailingNewline = false
+	return &c
+}
+
+// relayout writes the compact output data of n with the layout of n, as a
+// top-level value.
+func (n *Normalizer) relayout(data []byte) ([]byte, error) {
+	if !n.pretty && !n.separators {
+		return data, nil
+	}
+	return n.newOutputParser(data).parseValue(nil)
+}
+
+// newOutputParser returns a parser rescanning data, which is the compact
+// output of n. The hooks changing the output are cleared, so that they don't
+// apply to their own output again, and numbers are kept as they are written.
+func (n *Normalizer) newOutputParser(data []byte) *parser {
+	out := *n
+	out.keyTransformer = nil
+	out.stringTransformer = nil
+	out.numberFormatter = keepNumber
+	return out.newParser(newSliceReader(data))
+}
+
+// keepNumber is a number formatter writing numbers unchanged.
+func keepNumber(raw []byte) ([]byte, error) {
+	return raw, nil
+}
+
+func (p *parser) readByte() (byte, error) {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return c, err
+	}
+	p.advance(rune(c), 1)
+	return c, nil
+}
+
+func (p *parser) unreadByte() error {
+	if err := p.r.UnreadByte(); err != nil {
+		return err
+	}
+	p.pos = p.prev
+	return nil
+}
+
+func (p *parser) readRune() (rune, int, error) {
+	ch, size, err := p.r.ReadRune()
+	if err != nil {
+		return ch, size, err
+	}
+	p.advance(ch, size)
+	return ch, size, nil
+}
+
+func (p *parser) advance(ch rune, size int) {
+	p.prev = p.pos
+	p.pos.offset += int64(size)
+	// lines end with \n, \r\n or a lone \r
+	if ch == '\n' && p.pos.cr {
+		p.pos.cr = false
+	} else if ch == '\n' || ch == '\r' {
+		p.pos.line++
+		p.pos.column = 1
+		p.pos.cr = ch == '\r'
+	} else {
+		p.pos.column++
+		p.pos.cr = false
+	}
+}
+
+// syntaxError reports a syntax error at the last byte read.
+func (p *parser) syntaxError() error {
+	return p.errorAt(nil)
+}
+
+// truncated converts io.EOF met in the middle of a value into a syntax error
+// at the end of the input, other errors are returned as is.
+func (p *parser) truncated(err error) error {
+	if err != io.EOF {
+		return err
+	}
+	return p.newSyntaxError(ErrUnexpectedEnd, p.pos)
+}
+
+// errorAt reports a syntax error with the given cause at the last byte read.
+func (p *parser) errorAt(err error) error {
+	return p.newSyntaxError(err, p.prev)
+}
+
+func (p *parser) newSyntaxError(err error, pos position) *SyntaxError {
+	serr := &SyntaxError{
+		Err:    err,
+		Offset: pos.offset,
+		Line:   pos.line,
+		Column: pos.column,
+		Path:   p.pathString(),
+	}
+	if sr, ok := p.r.(*sliceReader); ok && pos.offset <= int64(len(sr.data)) {
+		serr.excerpt, serr.excerptAt = excerpt(sr.data, int(pos.offset))
+	}
+	return serr
+}
+
+// countNode accounts for a parsed value or key.
+func (p *parser) countNode() error {
+	if p.maxTotalNodes > 0 {
+		p.nodes++
+		if p.nodes > p.maxTotalNodes {
+			return ErrMaxNodesExceeded
+		}
+	}
+	return nil
+}
+
+// keepFailed records the output of the innermost container failing with err,
+// or of a complete value followed by trailing data, for NormalizePartial.
+func (p *parser) keepFailed(dst []byte, err error) {
+	if err != nil && p.keepPartial && p.partial == nil {
+		p.partial = dst
+	}
+}
+
+// enter accounts for a nested container, it is paired with leave.
+func (p *parser) enter() error {
+	p.depth++
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		return ErrMaxDepthExceeded
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// parseDocument parses a single top-level value which may only be surrounded
+// by filler symbols.
+func (p *parser) parseDocument() ([]byte, error) {
+	data, err := p.parseFirstValue(p.outputBuffer())
+	if err != nil {
+		return nil, err
+	}
+
 	for {
-		if c, err := r.ReadByte(); err != nil {
+		if err := p.skipFillers(); err != nil {
+			return nil, err
+		}
+		if _, err := p.readByte(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		} else if !p.multipleValues {
+			err := p.errorAt(ErrTrailingData)
+			p.keepFailed(data, err)
+			return nil, err
+		}
+		p.unreadByte()
+
+		if !p.discard {
+			data = append(data, '\n')
+		}
+		if err := p.checkOutputSize(data); err != nil {
+			return nil, err
+		}
+		if data, err = p.parseTopLevel(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.trailingNewline && !p.discard {
+		data = append(data, '\n')
+	}
+	if err := p.checkOutputSize(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// outputBuffer returns the buffer the output of the whole input is appended
+// to. Its capacity is the size of the input, when known, so that large
+// documents are not copied every time the output grows: the output is about
+// as large as the input, as unless pretty printing, normalization mostly drops
+// filler symbols.
+func (p *parser) outputBuffer() []byte {
+	if p.discard {
+		return nil
+	}
+	var size int
+	switch r := p.r.(type) {
+	case *sliceReader:
+		size = len(r.data) - r.off
+	case *bytes.Reader:
+		size = r.Len()
+	case *strings.Reader:
+		size = r.Len()
+	}
+	if size < minOutputBuffer {
+		return nil
+	}
+	return make([]byte, 0, size)
+}
+
+// minOutputBuffer is the input size from which the output buffer is sized
+// up front, smaller documents grow their output as needed.
+const minOutputBuffer = 1 << 10
+
+// parseFirstValue appends the value at the start of the input, after an
+// optional byte order mark and filler symbols, to dst.
+func (p *parser) parseFirstValue(dst []byte) ([]byte, error) {
+	if err := p.skipBOM(); err != nil {
+		return nil, err
+	}
+	if err := p.skipFillers(); err != nil {
+		return nil, err
+	}
+	if _, err := p.readByte(); err == io.EOF {
+		return nil, ErrEmptyInput
+	} else if err != nil {
+		return nil, err
+	}
+	p.unreadByte()
+
+	return p.parseTopLevel(dst)
+}
+
+// parseTopLevel appends the normalized next top-level value to dst, checking
+// that its kind is allowed.
+func (p *parser) parseTopLevel(dst []byte) ([]byte, error) {
+	if p.version != "" && p.version != canonicalVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	if p.allowedTopLevel == 0 {
+		return p.parseValue(dst)
+	}
+
+	if err := p.enterValue(); err != nil {
+		return nil, err
+	}
+	c, err := p.readByte()
+	if err != nil {
+		return nil, p.truncated(err)
+	}
+	kind, ok, err := p.kindAt(c)
+	if err != nil {
+		return nil, err
+	}
+	if ok && p.allowedTopLevel&(1<<uint(kind)) == 0 {
+		return nil, &TopLevelError{Kind: kind}
+	}
+	return p.parseValueAt(dst, c)
+}
+
+// skipBOM consumes a UTF-8 byte order mark at the start of the input when
+// stripping it is enabled. The mark does not count as a column.
+func (p *parser) skipBOM() error {
+	if !p.stripBOM {
+		return nil
+	}
+	if c, err := p.readByte(); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if c != 0xEF {
+		return p.unreadByte()
+	}
+	// no value starts with 0xEF, so anything other than a complete mark is
+	// a syntax error
+	for _, expected := range []byte{0xBB, 0xBF} {
+		if c, err := p.readByte(); err != nil {
+			return p.truncated(err)
+		} else if c != expected {
+			return p.syntaxError()
+		}
+	}
+	p.pos.column = 1
+	return nil
+}
+
+func (p *parser) skipFillers() error {
+	for {
+		if c, err := p.readByte(); err != nil {
 			if err == io.EOF {
 				return nil
 			}
 			return err
 		} else if c == ' ' || c == '\n' || c == '\r' || c == '\t' {
 			continue
+		} else if p.unicodeWhitespace && (c == '\v' || c == '\f') {
+			continue
+		} else if p.unicodeWhitespace && c >= utf8.RuneSelf {
+			// no token starts with a non-ASCII symbol, so anything but a
+			// space is an error
+			p.unreadByte()
+			if ch, _, err := p.readRune(); err != nil {
+				return err
+			} else if !isUnicodeSpace(ch) {
+				return p.syntaxError()
+			}
+			continue
+		} else if c == '/' && p.comments {
+			if err := p.skipComment(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		p.unreadByte()
+		return nil
+	}
+}
+
+// isUnicodeSpace reports whether ch is a non-ASCII ECMAScript white space or
+// line terminator.
+func isUnicodeSpace(ch rune) bool {
+	switch ch {
+	case '\u00A0', '\uFEFF', '\u2028', '\u2029':
+		return true
+	}
+	return unicode.Is(unicode.Zs, ch)
+}
+
+// skipComment skips a // line or /* block */ comment, the leading slash is
+// already consumed.
+func (p *parser) skipComment() error {
+	c, err := p.readByte()
+	if err == io.EOF {
+		return p.syntaxError()
+	} else if err != nil {
+		return err
+	}
+
+	switch c {
+	case '/':
+		for {
+			if c, err := p.readByte(); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			} else if c == '\n' {
+				return nil
+			}
+		}
+	case '*':
+		star := false
+		for {
+			if c, err := p.readByte(); err == io.EOF {
+				return p.syntaxError()
+			} else if err != nil {
+				return err
+			} else if c == '/' && star {
+				return nil
+			} else {
+				star = c == '*'
+			}
+		}
+	}
+	return p.syntaxError()
+}
+
+func (p *parser) parseName() (string, error) {
+	bp := getBytes()
+	buf, err := p.appendName((*bp)[:0])
+	if err != nil {
+		putBytes(bp, *bp)
+		return "", err
+	}
+	name := string(buf)
+	putBytes(bp, buf)
+
+	if p.keyNFC {
+		name = norm.NFC.String(name)
+	}
+	if p.keyTransformer != nil {
+		if val, err := p.keyTransformer(name); err != nil {
+			return "", err
+		} else {
+			name = val
+		}
+	}
+
+	return name, nil
+}
+
+// appendName appends the decoded next key to buf and consumes the colon
+// following it along with the surrounding filler symbols.
+func (p *parser) appendName(buf []byte) ([]byte, error) {
+	if c, err := p.readByte(); err != nil {
+		return nil, p.truncated(err)
+	} else if p.isQuote(c) {
+		if buf, err = p.decodeString(buf, c); err != nil {
+			return nil, err
+		}
+	} else if p.unquotedKeys && isIdentifierStart(c) {
+		if buf, err = p.appendIdentifier(buf, c); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, p.syntaxError()
+	}
+
+	if err := p.skipFillers(); err != nil {
+		return nil, err
+	}
+
+	if c, err := p.readByte(); err != nil {
+		return nil, p.truncated(err)
+	} else if c != ':' {
+		return nil, p.syntaxError()
+	}
+
+	if err := p.skipFillers(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// appendIdentifier appends the rest of an unquoted key starting with first to
+// buf.
+func (p *parser) appendIdentifier(buf []byte, first byte) ([]byte, error) {
+	buf = append(buf, first)
+	for {
+		if p.maxStringLength > 0 && len(buf) > p.maxStringLength {
+			return nil, ErrTokenTooLong
+		}
+		c, err := p.readByte()
+		if err != nil {
+			return nil, p.truncated(err)
 		}
+		if !isIdentifierStart(c) && !isDigit(c) {
+			p.unreadByte()
+			return buf, nil
+		}
+		buf = append(buf, c)
+	}
+}
+
+// isIdentifier reports whether s may be written as an unquoted key.
+func isIdentifier(s string) bool {
+	if s == "" || !isIdentifierStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentifierStart(s[i]) && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentifierStart reports whether c may start an unquoted key.
+func isIdentifierStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$'
+}
+
+// parseValue appends the normalized next value to dst.
+func (p *parser) parseValue(dst []byte) ([]byte, error) {
+	if err := p.enterValue(); err != nil {
+		return nil, err
+	}
+	c, err := p.readByte()
+	if err != nil {
+		return nil, p.truncated(err)
+	}
+	return p.parseValueAt(dst, c)
+}
+
+// enterValue accounts for the next value before it is parsed.
+func (p *parser) enterValue() error {
+	if p.ctx != nil {
+		if p.values%contextCheckInterval == 0 {
+			if err := p.ctx.Err(); err != nil {
+				return err
+			}
+		}
+		p.values++
+	}
+	return p.countNode()
+}
+
+// parseValueAt appends the normalized value starting with c, which has just
+// been read, to dst.
+func (p *parser) parseValueAt(dst []byte, c byte) (_ []byte, err error) {
+	switch c {
+	case '{':
+		dst, err = p.parseObject(dst)
+	case '[':
+		dst, err = p.parseArray(dst)
+	case '"', '\'':
+		if !p.isQuote(c) {
+			return nil, p.syntaxError()
+		}
+		dst, err = p.parseString(dst, c)
+	case 'n':
+		dst, err = p.parseNull(dst)
+	case 'N', 'I':
+		if c == 'N' && p.caseInsensitiveLit {
+			var kind Kind
+			if kind, _, err = p.kindAt(c); err != nil {
+				return nil, err
+			} else if kind == KindNull {
+				dst, err = p.parseNull(dst)
+				break
+			}
+		}
+		if !p.allowNonFinite {
+			return nil, p.syntaxError()
+		}
+		lit := "NaN"
+		if c == 'I' {
+			lit = "Infinity"
+		}
+		dst, err = p.parseNonFinite(dst, lit, lit[1:])
+	case 't', 'f':
+		dst, err = p.parseBool(dst, c)
+	case 'T', 'F':
+		if !p.caseInsensitiveLit {
+			return nil, p.syntaxError()
+		}
+		dst, err = p.parseBool(dst, c|0x20)
+	default:
+		if !((c >= '0' && c <= '9') || c == '-') {
+			return nil, p.syntaxError()
+		}
+		p.unreadByte()
+		dst, err = p.parseNumber(dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.checkOutputSize(dst); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
 
-		r.UnreadByte()
-		return nil
+// checkOutputSize fails once the output dst is larger than allowed.
+func (p *parser) checkOutputSize(dst []byte) error {
+	if p.maxOutputSize > 0 && !p.discard && len(dst) > p.maxOutputSize {
+		return ErrOutputTooLarge
 	}
+	return nil
 }
 
-func parseName(r *bytes.Reader) (string, error) {
-	var name []byte
+// omitted reports whether an object member with the value val is dropped.
+func (p *parser) omitted(val []byte) bool {
+	return p.omitNulls && string(val) == "null" || p.omitEmpty && isEmptyContainer(val)
+}
 
-	if c, err := r.ReadByte(); err != nil {
-		return "", err
-	} else if c != '"' {
-		return "", JsonSyntaxError
-	}
+func isEmptyContainer(val []byte) bool {
+	return string(val) == "{}" || string(val) == "[]"
+}
 
-	if buf, err := parseString(r); err != nil {
-		return "", err
-	} else {
-		name = buf
+// parseClosing consumes the closing bracket end if it is the next token.
+func (p *parser) parseClosing(end byte) (bool, error) {
+	if err := p.skipFillers(); err != nil {
+		return false, err
 	}
-
-	if err := skipFillers(r); err != nil {
-		return "", err
+	if c, err := p.readByte(); err != nil {
+		return false, p.truncated(err)
+	} else if c == end {
+		return true, nil
 	}
+	p.unreadByte()
+	return false, nil
+}
 
-	if c, err := r.ReadByte(); err != nil {
-		return "", err
-	} else if c != ':' {
-		return "", JsonSyntaxError
+// _ObjItem is an object member, start and end delimit its key and value in
+// the output.
+type _ObjItem struct {
+	name    string // decoded key
+	start   int
+	end     int
+	omitted bool // dropped once duplicate keys are handled
+}
+
+// parseObject appends the normalized object to dst. Members are written in
+// input order right away and only moved when sorting or the duplicate key
+// policy changes their order.
+func (p *parser) parseObject(dst []byte) (_ []byte, err error) {
+	if err := p.enter(); err != nil {
+		return nil, err
 	}
+	defer p.leave()
+	defer func() {
+		p.keepFailed(dst, err)
+	}()
 
-	if err := skipFillers(r); err != nil {
-		return "", err
+	op := getItems()
+	obj := (*op)[:0]
+	defer func() {
+		putItems(op, obj)
+	}()
+
+	// members keep their input order when they are neither sorted nor
+	// deduplicated, keys are then written from a scratch buffer without being
+	// kept
+	streamed := !p.sortKeys && !p.jcs && p.duplicateKeys == DuplicateKeysKeepAll &&
+		!p.keyNFC && p.keyTransformer == nil
+	var key []byte
+	if streamed {
+		kp := getBytes()
+		key = (*kp)[:0]
+		defer func() {
+			putBytes(kp, key)
+		}()
 	}
 
-	return string(name), nil
-}
+	// omitted members take part in the duplicate key policy, they are only
+	// dropped afterwards
+	dedup := p.duplicateKeys != DuplicateKeysKeepAll || p.jcs
+
+	start := len(dst)
+	dst = append(dst, '{')
 
-func parseValue(r *bytes.Reader) ([]byte, error) {
-	if c, err := r.ReadByte(); err != nil {
+	if closed, err := p.parseClosing('}'); err != nil {
 		return nil, err
-	} else {
-		switch c {
-		case '{':
-			if data, err := parseObject(r); err != nil {
-				return nil, err
-			} else {
-				return data, nil
+	} else if closed {
+		return append(dst, '}'), nil
+	}
+
+	pathLen := len(p.path)
+	for {
+		var name string
+
+		if err := p.skipFillers(); err != nil {
+			return nil, err
+		}
+		if streamed {
+			var val []byte
+			if val, err = p.appendName(key[:0]); err == nil {
+				key = val
 			}
-		case '[':
-			if data, err := parseArray(r); err != nil {
+		} else {
+			name, err = p.parseName()
+		}
+		if err != nil {
+			if more, err := p.recoverMember(err); err != nil {
 				return nil, err
-			} else {
-				return data, nil
+			} else if more {
+				continue
 			}
-		case '"':
-			if data, err := parseString(r); err != nil {
-				return nil, err
-			} else {
-				return data, nil
+			break
+		}
+		if err := p.countNode(); err != nil {
+			return nil, err
+		}
+
+		if err := p.skipFillers(); err != nil {
+			return nil, err
+		}
+
+		mark := len(dst)
+		if !p.discard {
+			if len(obj) != 0 {
+				dst = p.appendItemSeparator(dst)
 			}
-		case 'n':
-			if data, err := parseNull(r); err != nil {
-				return nil, err
+			dst = p.appendIndent(dst, p.depth)
+		}
+		it := _ObjItem{name: name, start: len(dst)}
+		seg := pathSegment{name: name, index: -1}
+		if streamed {
+			seg.key = key
+		}
+		if !p.discard {
+			if streamed {
+				dst = p.appendString(dst, key)
 			} else {
-				return data, nil
+				dst = p.appendString(dst, []byte(name))
 			}
-		case 't':
-			fallthrough
-		case 'f':
-			if data, err := parseBool(c, r); err != nil {
+			dst = p.appendKeySeparator(dst)
+		}
+		valStart := len(dst)
+		p.path = append(p.path, seg)
+		if val, err := p.parseValue(dst); err != nil {
+			more, err := p.recoverMember(err)
+			if err != nil {
 				return nil, err
-			} else {
-				return data, nil
 			}
-		default:
-			if c >= '0' && c <= '9' {
-				r.UnreadByte()
-				if data, err := parseNumber(r); err != nil {
+			p.path = p.path[:pathLen]
+			dst = dst[:mark]
+			if more {
+				continue
+			}
+			break
+		} else {
+			dst = val
+		}
+		p.path = p.path[:len(p.path)-1]
+		if p.discard {
+			dst = dst[:mark]
+			obj = append(obj, it)
+		} else if it.omitted = p.omitted(dst[valStart:]); it.omitted && !dedup {
+			dst = dst[:mark]
+		} else {
+			it.end = len(dst)
+			obj = append(obj, it)
+		}
+
+		if err := p.skipFillers(); err != nil {
+			return nil, err
+		}
+
+		if c, err := p.readByte(); err != nil {
+			return nil, p.truncated(err)
+		} else {
+			if c == ',' {
+				if !p.trailingCommas {
+					continue
+				}
+				if closed, err := p.parseClosing('}'); err != nil {
 					return nil, err
-				} else {
-					return data, nil
+				} else if !closed {
+					continue
 				}
-			} else {
-				return nil, JsonSyntaxError
+				break
+			} else if c == '}' {
+				break
+			}
+			if more, err := p.recoverMember(p.syntaxError()); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+	}
+
+	count := len(obj)
+	if dedup {
+		if val, err := p.dedupKeys(obj); err != nil {
+			if err := p.recordDuplicate(err); err != nil {
+				return nil, err
 			}
+		} else {
+			obj = dropOmitted(val)
 		}
 	}
+
+	if p.discard {
+		return dst[:start], nil
+	}
+
+	if p.sortKeys || p.jcs {
+		sort.SliceStable(obj, func(i, j int) bool {
+			return p.keyLess(obj[i].name, obj[j].name)
+		})
+	}
+
+	if len(obj) != count || !inOrder(obj) {
+		dst = p.reorder(dst, start+1, obj, false)
+	}
+	if len(obj) != 0 {
+		dst = p.appendIndent(dst, p.depth-1)
+	}
+	dst = append(dst, '}')
+
+	return dst, nil
 }
 
-func parseObject(r *bytes.Reader) ([]byte, error) {
-	type _ObjItem struct {
-		name  string
-		value []byte
+// inOrder reports whether the segments are still in output order.
+func inOrder(segs []_ObjItem) bool {
+	for i := 1; i < len(segs); i++ {
+		if segs[i].start < segs[i-1].start {
+			return false
+		}
 	}
-	obj := make([]_ObjItem, 0, 16)
+	return true
+}
 
-	for {
-		var name string
+// reorder rewrites the members or elements following offset in the order of
+// segs, dropping the segments left out, with separators and indentation
+// recomputed. perLine puts each segment on a line of its own.
+func (p *parser) reorder(dst []byte, offset int, segs []_ObjItem, perLine bool) []byte {
+	bp := getBytes()
+	tmp := append((*bp)[:0], dst[offset:]...)
+	dst = dst[:offset]
+	for i, seg := range segs {
+		if i != 0 {
+			dst = p.appendItemSeparator(dst)
+		}
+		dst = p.appendIndent(dst, p.depth)
+		if perLine {
+			dst = append(dst, '\n')
+		}
+		dst = append(dst, tmp[seg.start-offset:seg.end-offset]...)
+	}
+	putBytes(bp, tmp)
+	return dst
+}
 
-		if err := skipFillers(r); err != nil {
-			return nil, err
+// keyLess is the ordering of object keys.
+func (p *parser) keyLess(a, b string) bool {
+	if p.jcs {
+		return utf16Less(a, b)
+	}
+	if p.sortDescending {
+		a, b = b, a
+	}
+	if p.keyComparator != nil {
+		return p.keyComparator(a, b)
+	}
+	if p.utf16KeySort {
+		return utf16Less(a, b)
+	}
+	if p.naturalKeySort {
+		if p.caseInsensitiveSort {
+			if la, lb := strings.ToLower(a), strings.ToLower(b); la != lb {
+				return naturalLess(la, lb)
+			}
 		}
-		if val, err := parseName(r); err != nil {
-			return nil, err
-		} else {
-			if val == "" {
-				return nil, JsonSyntaxError
+		return naturalLess(a, b)
+	}
+	if p.caseInsensitiveSort {
+		if la, lb := strings.ToLower(a), strings.ToLower(b); la != lb {
+			return la < lb
+		}
+	}
+	return a < b
+}
+
+// naturalLess orders strings byte-wise except for runs of ASCII digits, which
+// compare by their numeric value, so that "item2" sorts before "item10".
+// Strings which only differ in leading zeros are ordered byte-wise.
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return a[i] < b[j]
 			}
-			name = val
+			i++
+			j++
+			continue
 		}
 
-		if err := skipFillers(r); err != nil {
-			return nil, err
+		// skip leading zeros and compare the significant digits, a longer
+		// run is larger
+		for i < len(a) && a[i] == '0' {
+			i++
 		}
-		if val, err := parseValue(r); err != nil {
-			return nil, err
-		} else {
-			if val == nil {
-				return nil, JsonSyntaxError
-			}
-			obj = append(obj, _ObjItem{name: name, value: val})
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		si, sj := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
 		}
+		if da, db := a[si:i], b[sj:j]; len(da) != len(db) {
+			return len(da) < len(db)
+		} else if da != db {
+			return da < db
+		}
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return a < b
+}
 
-		if err := skipFillers(r); err != nil {
-			return nil, err
+// dedupKeys applies the duplicate key policy to the members of an object.
+func (p *parser) dedupKeys(obj []_ObjItem) ([]_ObjItem, error) {
+	seen := make(map[string]int, len(obj))
+	res := obj[:0]
+	for _, it := range obj {
+		idx, ok := seen[it.name]
+		if !ok {
+			seen[it.name] = len(res)
+			res = append(res, it)
+			continue
 		}
 
-		if c, err := r.ReadByte(); err != nil {
-			return nil, err
-		} else {
-			if c == ',' {
-				continue
-			} else if c == '}' {
-				break
-			}
-			return nil, JsonSyntaxError
+		policy := p.duplicateKeys
+		if p.jcs {
+			policy = DuplicateKeysError
 		}
-	}
 
-	sort.Slice(obj, func(i, j int) bool {
-		return obj[i].name < obj[j].name
-	})
+		switch policy {
+		case DuplicateKeysError:
+			return nil, &DuplicateKeyError{Key: it.name}
+		case DuplicateKeysKeepLast:
+			res[idx].start, res[idx].end, res[idx].omitted = it.start, it.end, it.omitted
+		}
+	}
+	return res, nil
+}
 
-	data := make([]byte, 1, 256) // TODO bytes.Buffer?
-	data[0] = '{'
-	first := true
+// dropOmitted removes the members omitted by WithOmitNulls or WithOmitEmpty.
+func dropOmitted(obj []_ObjItem) []_ObjItem {
+	res := obj[:0]
 	for _, it := range obj {
-		if first {
-			first = false
-		} else {
-			data = append(data, ',')
+		if !it.omitted {
+			res = append(res, it)
 		}
-		data = append(data, it.name...)
-		data = append(data, ':')
-		data = append(data, it.value...)
 	}
-	data = append(data, '}')
+	return res
+}
 
-	return data, nil
+// appendIndent starts a new line indented to the given depth when pretty
+// printing is enabled.
+func (p *parser) appendIndent(data []byte, depth int) []byte {
+	if !p.pretty || p.jcs {
+		return data
+	}
+	data = append(data, '\n')
+	data = append(data, p.prefix...)
+	for i := 0; i < depth; i++ {
+		data = append(data, p.indent...)
+	}
+	return data
+}
+
+// appendItemSeparator appends the separator written between the members of
+// an object or array.
+func (p *parser) appendItemSeparator(data []byte) []byte {
+	if p.separators && !p.jcs {
+		return append(data, p.itemSeparator...)
+	}
+	return append(data, ',')
 }
 
-func parseArray(r *bytes.Reader) ([]byte, error) {
-	data := make([]byte, 1, 256) // TODO bytes.Buffer?
-	data[0] = '['
+// appendKeySeparator appends the separator written between a key and its
+// value.
+func (p *parser) appendKeySeparator(data []byte) []byte {
+	if p.separators && !p.jcs {
+		return append(data, p.keySeparator...)
+	}
+	data = append(data, ':')
+	if p.pretty && !p.jcs {
+		data = append(data, ' ')
+	}
+	return data
+}
 
-	for {
-		if err := skipFillers(r); err != nil {
+// parseArray appends the normalized array to dst.
+func (p *parser) parseArray(dst []byte) (_ []byte, err error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	defer func() {
+		p.keepFailed(dst, err)
+	}()
+
+	start := len(dst)
+	dst = append(dst, '[')
+
+	if closed, err := p.parseClosing(']'); err != nil {
+		return nil, err
+	} else if closed {
+		return append(dst, ']'), nil
+	}
+
+	// elems delimits the elements when they are sorted or deduplicated
+	// before output
+	var elems []_ObjItem
+
+	for index := 0; ; index++ {
+		if err := p.skipFillers(); err != nil {
 			return nil, err
 		}
-		if val, err := parseValue(r); err != nil {
-			return nil, err
-		} else {
-			if val == nil {
-				return nil, JsonSyntaxError
+
+		mark := len(dst)
+		if !p.discard {
+			if len(dst) > start+1 {
+				dst = p.appendItemSeparator(dst)
+			}
+			dst = p.appendIndent(dst, p.depth)
+			if p.perLine() {
+				dst = append(dst, '\n')
+			}
+		}
+		valStart := len(dst)
+		pathLen := len(p.path)
+		p.path = append(p.path, pathSegment{index: index})
+		if val, err := p.parseValue(dst); err != nil {
+			more, err := p.recoverMember(err)
+			if err != nil {
+				return nil, err
 			}
-			if len(data) > 1 {
-				data = append(data, ',')
+			p.path = p.path[:pathLen]
+			dst = dst[:mark]
+			if more {
+				continue
 			}
-			data = append(data, val...)
+			return p.closeArray(dst, start, elems), nil
+		} else {
+			dst = val
+		}
+		p.path = p.path[:len(p.path)-1]
+		if p.discard || p.omitEmpty && isEmptyContainer(dst[valStart:]) {
+			dst = dst[:mark]
+		} else if p.sortArrays || p.dedupArrays {
+			elems = append(elems, _ObjItem{start: valStart, end: len(dst)})
 		}
 
-		if err := skipFillers(r); err != nil {
+		if err := p.skipFillers(); err != nil {
 			return nil, err
 		}
 
-		if c, err := r.ReadByte(); err != nil {
-			return nil, err
+		if c, err := p.readByte(); err != nil {
+			return nil, p.truncated(err)
 		} else {
 			if c == ',' {
-				continue
+				if !p.trailingCommas {
+					continue
+				}
+				if closed, err := p.parseClosing(']'); err != nil {
+					return nil, err
+				} else if !closed {
+					continue
+				}
+				return p.closeArray(dst, start, elems), nil
 			} else if c == ']' {
-				data = append(data, ']')
-				return data, nil
+				return p.closeArray(dst, start, elems), nil
+			}
+			if more, err := p.recoverMember(p.syntaxError()); err != nil {
+				return nil, err
+			} else if !more {
+				return p.closeArray(dst, start, elems), nil
+			}
+		}
+	}
+}
+
+// closeArray sorts and deduplicates the elements of the array starting at
+// start as configured and appends the closing bracket.
+func (p *parser) closeArray(dst []byte, start int, elems []_ObjItem) []byte {
+	if len(elems) != 0 {
+		count := len(elems)
+		if p.sortArrays {
+			sort.Slice(elems, func(i, j int) bool {
+				return bytes.Compare(dst[elems[i].start:elems[i].end], dst[elems[j].start:elems[j].end]) < 0
+			})
+		}
+		if p.dedupArrays {
+			seen := make(map[string]bool, len(elems))
+			res := elems[:0]
+			for _, el := range elems {
+				if val := string(dst[el.start:el.end]); !seen[val] {
+					seen[val] = true
+					res = append(res, el)
+				}
 			}
-			return nil, JsonSyntaxError
+			elems = res
+		}
+		if len(elems) != count || !inOrder(elems) {
+			dst = p.reorder(dst, start+1, elems, p.perLine())
+		}
+	}
+	if len(dst) > start+1 {
+		dst = p.appendIndent(dst, p.depth-1)
+		if p.perLine() {
+			dst = append(dst, '\n')
 		}
 	}
+	return append(dst, ']')
 }
 
-func parseString(r *bytes.Reader) ([]byte, error) {
-	buf := make([]byte, 1, 128)
-	escaping := false
+// perLine reports whether the elements of the array being parsed are put on
+// lines of their own, see WithTopLevelArrayPerLine.
+func (p *parser) perLine() bool {
+	return p.topLevelArrayPerLine && p.depth == 1 && !p.pretty && !p.jcs
+}
 
-	buf[0] = '"'
+// isQuote reports whether c opens a string.
+func (p *parser) isQuote(c byte) bool {
+	return c == '"' || c == '\'' && p.singleQuotes
+}
 
-	for {
-		ch, _, err := r.ReadRune()
-		if err != nil {
+// parseString appends the normalized string delimited by quote to dst, the
+// opening quote is already consumed.
+func (p *parser) parseString(dst []byte, quote byte) ([]byte, error) {
+	bp := getBytes()
+	buf, err := p.decodeString((*bp)[:0], quote)
+	if err != nil {
+		putBytes(bp, *bp)
+		return nil, err
+	}
+
+	if p.valueNFC && !norm.NFC.IsNormal(buf) {
+		buf = append(buf[:0], norm.NFC.Bytes(buf)...)
+	}
+	if p.trimStringValues {
+		buf = append(buf[:0], bytes.TrimSpace(buf)...)
+	}
+	if p.stringTransformer != nil {
+		if val, err := p.stringTransformer(string(buf)); err != nil {
+			putBytes(bp, buf)
 			return nil, err
+		} else {
+			buf = append(buf[:0], val...)
+		}
+	}
+	if p.recurseEmbedded && !p.discard {
+		if val, ok := p.normalizeEmbedded(buf); ok {
+			buf = append(buf[:0], val...)
+		}
+	}
+
+	if !p.discard {
+		dst = p.appendString(dst, buf)
+	}
+	putBytes(bp, buf)
+	return dst, nil
+}
+
+// normalizeEmbedded normalizes the object or array held in the decoded
+// string s, it reports false when s holds anything else or fails to
+// normalize. Other values are not taken for JSON, so that strings like "1.0"
+// or "null" stay text. The content is normalized with the settings of p, but
+// always compact and as a single value.
+func (p *parser) normalizeEmbedded(s []byte) ([]byte, bool) {
+	if s = bytes.TrimLeft(s, " \t\r\n"); len(s) == 0 || s[0] != '{' && s[0] != '[' {
+		return nil, false
+	}
+	n := *p.Normalizer
+	n.pretty = false
+	n.topLevelArrayPerLine = false
+	n.trailingNewline = false
+	n.multipleValues = false
+	n.allowedTopLevel = 0
+	val, err := n.newParser(newSliceReader(s)).parseDocument()
+	return val, err == nil
+}
+
+// appendPlainRun appends c, the last byte read from sr, and the printable
+// ASCII bytes following it up to a quote, a backslash or any other byte to
+// buf, consuming them from sr.
+func (p *parser) appendPlainRun(buf []byte, sr *sliceReader, c, quote byte) ([]byte, error) {
+	start, end := sr.off-1, sr.off
+	for end < len(sr.data) {
+		if b := sr.data[end]; b < ' ' || b >= utf8.RuneSelf || b == quote || b == '\\' {
+			break
 		}
+		end++
+	}
+
+	n := end - start
+	buf = growBytes(buf, n)
+	buf = append(buf, sr.data[start:end]...)
+	if p.maxStringLength > 0 && len(buf) > p.maxStringLength {
+		return nil, ErrTokenTooLong
+	}
 
-		var chBuf [4]byte
-		size := utf8.EncodeRune(chBuf[:], ch)
-		buf = append(buf, chBuf[:size]...)
+	// the run holds no line break, the position moves along the line
+	sr.off = end
+	p.prev = p.pos
+	p.prev.offset += int64(n - 2)
+	p.prev.column += n - 2
+	p.pos.offset += int64(n - 1)
+	p.pos.column += n - 1
+	p.pos.cr = false
+	return buf, nil
+}
 
-		if ch == '\\' {
-			if escaping {
-				escaping = false
-			} else {
-				escaping = true
+// decodeString reads the rest of a string delimited by quote, the opening
+// quote is already consumed, and appends its content with escape sequences
+// decoded to buf. A string cut short by the end of the input is a syntax
+// error.
+func (p *parser) decodeString(buf []byte, quote byte) ([]byte, error) {
+	p.inString = quote
+	for {
+		c, err := p.readByte()
+		if err != nil {
+			return nil, p.truncated(err)
+		}
+
+		// ASCII is copied as is, only multi-byte sequences are decoded
+		var ch rune
+		if c >= ' ' && c < utf8.RuneSelf && c != quote && c != '\\' {
+			if sr, ok := p.r.(*sliceReader); ok {
+				// copy the whole run from the input
+				if buf, err = p.appendPlainRun(buf, sr, c, quote); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		if c == quote {
+			p.inString = 0
+			return buf, nil
+		} else if c == '\\' {
+			if ch, err = p.parseEscape(); err != nil {
+				return nil, err
+			}
+		} else if c < ' ' {
+			// control characters have to be escaped
+			return nil, p.syntaxError()
+		} else if c < utf8.RuneSelf {
+			if len(buf) == cap(buf) {
+				buf = growBytes(buf, 1)
 			}
+			buf = append(buf, c)
+			if p.maxStringLength > 0 && len(buf) > p.maxStringLength {
+				return nil, ErrTokenTooLong
+			}
+			continue
 		} else {
-			if ch == '"' {
-				if !escaping {
-					return buf, nil
-				}
+			p.unreadByte()
+			var size int
+			if ch, size, err = p.readRune(); err != nil {
+				return nil, p.truncated(err)
+			} else if ch == utf8.RuneError && size == 1 && !p.replaceInvalidUTF8 {
+				return nil, p.errorAt(ErrInvalidUTF8)
 			}
-			escaping = false
+		}
+
+		if cap(buf)-len(buf) < utf8.UTFMax {
+			buf = growBytes(buf, utf8.UTFMax)
+		}
+		buf = utf8.AppendRune(buf, ch)
+		if p.maxStringLength > 0 && len(buf) > p.maxStringLength {
+			return nil, ErrTokenTooLong
 		}
 	}
+}
 
-	return nil, nil
+func (p *parser) parseBool(dst []byte, startByte byte) ([]byte, error) {
+	lit := "true"
+	if startByte != 't' {
+		lit = "false"
+	}
+	if err := p.readLiteral(lit[1:]); err != nil {
+		return nil, err
+	}
+	return append(dst, lit...), nil
 }
 
-func parseBool(startByte byte, r *bytes.Reader) ([]byte, error) {
-	var buf []byte
-	if startByte == 't' {
-		buf = []byte("true")
-	} else {
-		buf = []byte("false")
+func (p *parser) parseNull(dst []byte) ([]byte, error) {
+	const lit = "null"
+	if err := p.readLiteral(lit[1:]); err != nil {
+		return nil, err
 	}
-	for _, expected := range buf[1:] {
-		c, err := r.ReadByte()
+	return append(dst, lit...), nil
+}
+
+// readLiteral consumes rest, the lowercase remainder of a literal. Its
+// letters may be in any case with case-insensitive literals.
+func (p *parser) readLiteral(rest string) error {
+	for i := 0; i < len(rest); i++ {
+		expected := rest[i]
+		c, err := p.readByte()
 		if err != nil {
-			return nil, err
+			return p.truncated(err)
 		}
-		if c != expected {
-			return nil, JsonSyntaxError
+		if c != expected && !(p.caseInsensitiveLit && c|0x20 == expected) {
+			return p.syntaxError()
 		}
 	}
-	return buf, nil
+	return nil
 }
 
-func parseNull(r *bytes.Reader) ([]byte, error) {
-	buf := []byte("null")
-	for _, expected := range buf[1:] {
-		c, err := r.ReadByte()
+// parseNonFinite reads the rest of the non-finite number lit and appends it
+// to dst, or null when non-finite numbers are replaced.
+func (p *parser) parseNonFinite(dst []byte, lit, rest string) ([]byte, error) {
+	for i := 0; i < len(rest); i++ {
+		c, err := p.readByte()
 		if err != nil {
-			return nil, err
+			return nil, p.truncated(err)
 		}
-		if c != expected {
-			return nil, JsonSyntaxError
+		if c != rest[i] {
+			return nil, p.syntaxError()
 		}
 	}
-	return buf, nil
+	if p.nonFiniteAsNull {
+		lit = "null"
+	}
+	return append(dst, lit...), nil
+}
+
+// appendNumber appends the canonical form of the number literal buf to dst.
+func (p *parser) appendNumber(dst, buf []byte) ([]byte, error) {
+	var data []byte
+	var err error
+	if p.jcs {
+		data, err = jcsNumber(buf)
+	} else if p.numberFormatter != nil {
+		if data, err = p.numberFormatter(buf); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err = canonicalNumber(buf)
+	}
+	if err == JsonSyntaxError {
+		return nil, p.syntaxError()
+	} else if err != nil {
+		return nil, err
+	}
+	return append(dst, data...), nil
 }
 
-func parseNumber(r *bytes.Reader) ([]byte, error) {
+func (p *parser) parseNumber(dst []byte) ([]byte, error) {
 	buf := make([]byte, 0, 32)
 	firstPoint := true
+	leadingZero := false
+	intDigits := 0
+	exponent := false
 
 	for {
-		c, err := r.ReadByte()
+		if p.maxNumberLength > 0 && len(buf) > p.maxNumberLength {
+			return nil, ErrTokenTooLong
+		}
+
+		c, err := p.readByte()
 		if err != nil {
-			if err == io.EOF && len(buf) != 0 {
-				return buf, nil
+			if err == io.EOF && len(buf) != 0 && buf[len(buf)-1] != '.' {
+				return p.appendNumber(dst, buf)
 			} else {
-				return nil, err
+				return nil, p.truncated(err)
 			}
 		}
 
+		// the decimal point has to be followed by a digit
+		if len(buf) != 0 && buf[len(buf)-1] == '.' && !(c >= '0' && c <= '9') {
+			return nil, p.syntaxError()
+		}
+
 		if c >= '0' && c <= '9' {
+			if !exponent && firstPoint {
+				if leadingZero {
+					return nil, p.syntaxError()
+				}
+				if c == '0' && intDigits == 0 {
+					leadingZero = true
+				}
+				intDigits++
+			}
 			buf = append(buf, c)
-		} else if c == '.' && firstPoint {
+		} else if c == '-' && len(buf) == 0 {
+			buf = append(buf, c)
+		} else if c == 'I' && p.allowNonFinite && len(buf) == 1 && buf[0] == '-' {
+			return p.parseNonFinite(dst, "-Infinity", "nfinity")
+		} else if c == '.' && firstPoint && !exponent && intDigits != 0 {
 			buf = append(buf, c)
 			firstPoint = false
-		} else if c == ',' || c == ']' || c == '}' || c == ' ' {
-			r.UnreadByte()
-			return buf, nil
+			leadingZero = false
+		} else if (c == 'e' || c == 'E') && !exponent && intDigits != 0 {
+			buf = append(buf, c)
+			exponent = true
+		} else if (c == '+' || c == '-') && exponent && (buf[len(buf)-1] == 'e' || buf[len(buf)-1] == 'E') {
+			buf = append(buf, c)
+		} else if c == ',' || c == ']' || c == '}' || c == ' ' || c == '\n' || c == '\r' || c == '\t' || (c == '/' && p.comments) ||
+			(p.unicodeWhitespace && (c == '\v' || c == '\f' || c >= utf8.RuneSelf)) {
+			p.unreadByte()
+			return p.appendNumber(dst, buf)
 		} else {
-			return nil, JsonSyntaxError
+			return nil, p.syntaxError()
 		}
 	}
 }
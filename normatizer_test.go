@@ -2,16 +2,22 @@ package normalizer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 )
 
 func TestParseString(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src))
-		data, err := parseString(r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseString(nil, '"')
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
@@ -21,16 +27,21 @@ func TestParseString(t *testing.T) {
 	check(`1"`, `"1"`, nil)
 	check(`abc"`, `"abc"`, nil)
 	check(`a\"bc"`, `"a\"bc"`, nil)
+	// the opening quote is already consumed: an empty string followed by 123
 	check(`"123`, `""`, nil)
 
-	check(`xyz`, ``, io.EOF)
+	check(`xyz`, ``, JsonSyntaxError)
+	check(``, ``, JsonSyntaxError)
+	check(`abc\`, ``, JsonSyntaxError)
+	check(`abc\u12`, ``, JsonSyntaxError)
+	check(`\uD83D\`, ``, JsonSyntaxError)
 }
 
 func TestParseBool(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src[1:]))
-		data, err := parseBool(src[0], r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src[1:])))
+		data, err := p.parseBool(nil, src[0])
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
@@ -42,14 +53,14 @@ func TestParseBool(t *testing.T) {
 	check(`a\"bc"`, ``, JsonSyntaxError)
 	check(`null`, ``, JsonSyntaxError)
 
-	check(`t`, ``, io.EOF)
+	check(`t`, ``, ErrUnexpectedEnd)
 }
 
 func TestParseNull(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src))
-		data, err := parseNull(r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseNull(nil)
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
@@ -59,14 +70,14 @@ func TestParseNull(t *testing.T) {
 	check(`ull`, `null`, nil)
 	check(`false`, ``, JsonSyntaxError)
 	check(`a\"bc"`, ``, JsonSyntaxError)
-	check(``, ``, io.EOF)
+	check(``, ``, ErrUnexpectedEnd)
 }
 
 func TestParseNumber(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src))
-		data, err := parseNumber(r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseNumber(nil)
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
@@ -75,26 +86,86 @@ func TestParseNumber(t *testing.T) {
 
 	check(`123`, `123`, nil)
 	check(`123.456`, `123.456`, nil)
+	check(`-12.5e3`, `-12500`, nil)
+	check(`1.0`, `1`, nil)
+	check(`1-`, ``, JsonSyntaxError)
+	check(`1e5e5`, ``, JsonSyntaxError)
 	check(`a\"bc"`, ``, JsonSyntaxError)
 	check(`1.2.3"`, ``, JsonSyntaxError)
-	check(``, ``, io.EOF)
+	check(``, ``, ErrUnexpectedEnd)
+}
+
+func TestParseNumberBareDecimalPoint(t *testing.T) {
+	check := func(src string, expectedError error) {
+		_, err := Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		}
+	}
+
+	check(`1.`, JsonSyntaxError)
+	check(`.5`, JsonSyntaxError)
+	check(`-.5`, JsonSyntaxError)
+	check(`1.e5`, JsonSyntaxError)
+	check(`[1.]`, JsonSyntaxError)
+	check(`[-.5]`, JsonSyntaxError)
+	check(`{"a":1.}`, JsonSyntaxError)
+	check(`{"a":.5}`, JsonSyntaxError)
+	check(`1.0`, nil)
+	check(`0.5`, nil)
+	check(`[1.5e3]`, nil)
+}
+
+func TestParseNumberLeadingZeros(t *testing.T) {
+	cases := []struct {
+		src           string
+		expected      string
+		expectedError error
+	}{
+		{`0`, `0`, nil},
+		{`0.5`, `0.5`, nil},
+		{`0.05`, `0.05`, nil},
+		{`10`, `10`, nil},
+		{`100`, `100`, nil},
+		{`01`, ``, JsonSyntaxError},
+		{`00`, ``, JsonSyntaxError},
+		{`007`, ``, JsonSyntaxError},
+		{`00.5`, ``, JsonSyntaxError},
+	}
+
+	for _, c := range cases {
+		p := New().newParser(bytes.NewReader([]byte(c.src)))
+		data, err := p.parseNumber(nil)
+		if !errors.Is(err, c.expectedError) {
+			t.Errorf("%v != %v, src: %s", err, c.expectedError, c.src)
+		} else if val := string(data); val != c.expected {
+			t.Errorf("%v != %v", val, c.expected)
+		}
+	}
 }
 
 func TestParseName(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src))
-		data, err := parseName(r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseName()
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
 		}
 	}
 
-	check(`"1":`, `"1"`, nil)
-	check(`"abc":`, `"abc"`, nil)
-	check(`"a\"bc"  :  `, `"a\"bc"`, nil)
-	check(`"xyz"`, ``, io.EOF)
+	check(`"1":`, `1`, nil)
+	check(`"abc":`, `abc`, nil)
+	check(`"a\"bc"  :  `, `a"bc`, nil)
+	check(`"":`, ``, nil)
+	check(`"a\\":`, `a\`, nil)
+	check(`"\\\"":`, `\"`, nil)
+	check(`"\u0000":`, "\x00", nil)
+	check(`"a\u0000\"\u0000":`, "a\x00\"\x00", nil)
+	check(`"\ud83d\ude00\/é":`, "😀/é", nil)
+	check(`"a\\"`+"\n:", `a\`, nil)
+	check(`"xyz"`, ``, ErrUnexpectedEnd)
 	check(`xyz`, ``, JsonSyntaxError)
 	check(`"xyz",`, ``, JsonSyntaxError)
 	check(`"xyz"}`, ``, JsonSyntaxError)
@@ -103,9 +174,9 @@ func TestParseName(t *testing.T) {
 
 func TestParseArray(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src))
-		data, err := parseArray(r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseArray(nil)
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
@@ -120,16 +191,22 @@ func TestParseArray(t *testing.T) {
 
 	check("  1, [2, \n 3]]", `[1,[2,3]]`, nil)
 
-	check(`1`, ``, io.EOF)
+	check(`]`, `[]`, nil)
+	check(` ]`, `[]`, nil)
+	check("\n\t]", `[]`, nil)
+	check(`[], [ ]]`, `[[],[]]`, nil)
+
+	check(`1`, ``, ErrUnexpectedEnd)
 	check(`1}`, ``, JsonSyntaxError)
 	check(`1,,]`, ``, JsonSyntaxError)
+	check(`1,]`, ``, JsonSyntaxError)
 }
 
 func TestParseObject(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src))
-		data, err := parseObject(r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseObject(nil)
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
@@ -144,6 +221,19 @@ func TestParseObject(t *testing.T) {
 	check(`"x": 1, "a": [{"b": "c", "a": 1}] }`, `{"a":[{"a":1,"b":"c"}],"x":1}`, nil)
 
 	check(`"c": 1, "a": 3, "b": 2}`, `{"a":3,"b":2,"c":1}`, nil)
+	check(`"b": 1, "": 2, "a\"": 3}`, `{"":2,"a\"":3,"b":1}`, nil)
+
+	// keys are sorted by their decoded bytes and escaped again on output
+	check(`"\\": 1, "\u0000": 2, "\"": 3, "": 4, "a\\": 5, "a": 6, "\ud83d\ude00": 7, "é": 8, "\/": 9, "a\u0000": 10}`,
+		`{"":4,"\u0000":2,"\"":3,"/":9,"\\":1,"a":6,"a\u0000":10,"a\\":5,"é":8,"😀":7}`, nil)
+	check(`"a\\": {"\\\\": [], "\\\"": null}, "a\"": "\\"}`, `{"a\"":"\\","a\\":{"\\\"":null,"\\\\":[]}}`, nil)
+	check(`"\u001f": 1, "\u0001": 2, "\t": 3, "\n": 4}`, `{"\u0001":2,"\t":3,"\n":4,"\u001f":1}`, nil)
+
+	check(`}`, `{}`, nil)
+	check(` }`, `{}`, nil)
+	check("\n}", `{}`, nil)
+	check(`"a": {}, "b": { } }`, `{"a":{},"b":{}}`, nil)
+	check(`"a": 1, }`, ``, JsonSyntaxError)
 
 	/*
 		check(`1,2]`, `[1,2]`, nil)
@@ -159,11 +249,43 @@ func TestParseObject(t *testing.T) {
 	*/
 }
 
+func TestParseObjectDuplicateKeysOrder(t *testing.T) {
+	var src, expected strings.Builder
+	src.WriteString(`{`)
+	expected.WriteString(`{`)
+	for i := 0; i < 100; i++ {
+		if i > 0 {
+			src.WriteString(`,`)
+			expected.WriteString(`,`)
+		}
+		fmt.Fprintf(&src, `"%c":%d,"a":%d`, 'z'-i%3, i, i)
+		fmt.Fprintf(&expected, `"a":%d`, i)
+	}
+	src.WriteString(`}`)
+	for _, key := range []byte("xyz") {
+		for i := 0; i < 100; i++ {
+			if 'z'-i%3 == int(key) {
+				fmt.Fprintf(&expected, `,"%c":%d`, key, i)
+			}
+		}
+	}
+	expected.WriteString(`}`)
+
+	for i := 0; i < 10; i++ {
+		data, err := Normalize([]byte(src.String()))
+		if err != nil {
+			t.Fatal(err)
+		} else if val := string(data); val != expected.String() {
+			t.Fatalf("%v != %v", val, expected.String())
+		}
+	}
+}
+
 func TestParseValue(t *testing.T) {
 	check := func(src, expected string, expectedError error) {
-		r := bytes.NewReader([]byte(src))
-		data, err := parseValue(r)
-		if err != expectedError {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseValue(nil)
+		if !errors.Is(err, expectedError) {
 			t.Errorf("%v != %v, src: %s", err, expectedError, src)
 		} else if val := string(data); val != expected {
 			t.Errorf("%v != %v", val, expected)
@@ -174,18 +296,546 @@ func TestParseValue(t *testing.T) {
 	check(`true`, `true`, nil)
 	check(`345`, `345`, nil)
 	check(`345.7`, `345.7`, nil)
+	check(`-345.70`, `-345.7`, nil)
+	check(`[1e2, 100.00, 100]`, `[100,100,100]`, nil)
 	check(`"abc"`, `"abc"`, nil)
 	check(`[1, 3, 2]`, `[1,3,2]`, nil)
 	check(`{"a":1}`, `{"a":1}`, nil)
 	check(`{"b": "c", "a": 1 }`, `{"a":1,"b":"c"}`, nil)
+	check(`[]`, `[]`, nil)
+	check(`[ ]`, `[]`, nil)
+	check("[\n\t]", `[]`, nil)
+	check(`{}`, `{}`, nil)
+	check(`{ }`, `{}`, nil)
+	check("{\n}", `{}`, nil)
+}
+
+func TestParseValueAppends(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		p := n.newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseValue([]byte("prefix:"))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(New(), `1.50`, `prefix:1.5`)
+	check(New(), `"x"`, `prefix:"x"`)
+	check(New(), `{"b": [3, {"d": 1, "c": 2}], "a": null}`, `prefix:{"a":null,"b":[3,{"c":2,"d":1}]}`)
+	check(New(WithSortArrays(true), WithDedupArrays(true)), `[3, 1, 3, [2, 1]]`, `prefix:[1,3,[1,2]]`)
+	check(New(WithOmitNulls(true)), `{"b": null, "a": 1, "c": null}`, `prefix:{"a":1}`)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepLast)), `{"b": 1, "a": 2, "b": 3}`, `prefix:{"a":2,"b":3}`)
+	check(New(WithIndent("", "  ")), `{"b": [1], "a": 2}`, "prefix:{\n  \"a\": 2,\n  \"b\": [\n    1\n  ]\n}")
+}
+
+func TestNormalize(t *testing.T) {
+	check := func(src, expected string, expectedError error) {
+		data, err := Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(`{"b": 1, "a": "x"}`, `{"a":"x","b":1}`, nil)
+	check(`{}  `, `{}`, nil)
+	check("  [1, 2]\n", `[1,2]`, nil)
+	check(`1`, `1`, nil)
+	check(`1 `, `1`, nil)
+
+	check(`1 2`, ``, JsonSyntaxError)
+	check(`true false`, ``, JsonSyntaxError)
+	check(`{"a":1}garbage`, ``, JsonSyntaxError)
+	check(`[1]]`, ``, JsonSyntaxError)
+
+	check(``, ``, ErrEmptyInput)
+	check(`   `, ``, ErrEmptyInput)
+	check("\n", ``, ErrEmptyInput)
+	check(" \t\r\n ", ``, ErrEmptyInput)
+
+	check(`"abc`, ``, JsonSyntaxError)
+	check(`{"a": "abc`, ``, JsonSyntaxError)
+	check(`{"abc`, ``, JsonSyntaxError)
+
+	check("\"a\tb\"", ``, JsonSyntaxError)
+	check("\"a\x00b\"", ``, JsonSyntaxError)
+	check("{\"a\nb\": 1}", ``, JsonSyntaxError)
+}
+
+func TestValid(t *testing.T) {
+	check := func(n *Normalizer, src string) {
+		_, err := n.Normalize([]byte(src))
+		if val := n.Valid([]byte(src)); val != (err == nil) {
+			t.Errorf("%v != %v, src: %s", val, err == nil, src)
+		}
+	}
+
+	for _, src := range []string{
+		`{"b": 1, "a": "x"}`,
+		`[1, {"d": [], "c": null}, [true, false]]`,
+		` "abc\n" `,
+		`-12.5e3`,
+		`{}`,
+		`[]`,
+		`{"a": {"a": {"a": 1}}}`,
+		``,
+		`   `,
+		`1 2`,
+		`[1,]`,
+		`{"a" 1}`,
+		`{"a": 1,}`,
+		`[1, 2`,
+		`"abc`,
+		`"\x"`,
+		`01`,
+		`1e`,
+		"\"\xff\"",
+		"\"a\tb\"",
+		"\"a\x00b\"",
+		`tru`,
+		`{"a":1,"a":2}`,
+		`[[[[1]]]]`,
+	} {
+		check(New(), src)
+		check(New(WithDuplicateKeys(DuplicateKeysError)), src)
+		check(New(WithMaxDepth(3)), src)
+		check(New(WithTrailingCommas(true)), src)
+	}
+
+	if !Valid([]byte(`{"a": [1, 2]}`)) {
+		t.Errorf("valid document reported as invalid")
+	}
+	if Valid([]byte(`{"a": [1, 2}`)) {
+		t.Errorf("invalid document reported as valid")
+	}
+	if Valid([]byte("\"a\tb\"")) || Valid([]byte("\"a\x00b\"")) {
+		t.Errorf("unescaped control character reported as valid")
+	}
+	if New(WithJCS(true)).Valid([]byte("[\"a\x1fb\"]")) {
+		t.Errorf("unescaped control character reported as valid with JCS")
+	}
+}
+
+func TestNormalizeString(t *testing.T) {
+	check := func(src, expected string, expectedError error) {
+		val, err := NormalizeString(src)
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(`{"b": 1, "a": "x"}`, `{"a":"x","b":1}`, nil)
+	check(`{}  `, `{}`, nil)
+	check("  [1, 2]\n", `[1,2]`, nil)
+	check(`1`, `1`, nil)
+	check(`1 `, `1`, nil)
+
+	check(`1 2`, ``, JsonSyntaxError)
+	check(`true false`, ``, JsonSyntaxError)
+	check(`{"a":1}garbage`, ``, JsonSyntaxError)
+	check(`[1]]`, ``, JsonSyntaxError)
+
+	check(``, ``, ErrEmptyInput)
+	check(`   `, ``, ErrEmptyInput)
+	check("\n", ``, ErrEmptyInput)
+}
+
+func TestNormalizeContext(t *testing.T) {
+	src := []byte(`{"b": [1, 2, {"d": null, "c": true}], "a": "x"}`)
+
+	data, err := NormalizeContext(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	} else if val := string(data); val != `{"a":"x","b":[1,2,{"c":true,"d":null}]}` {
+		t.Errorf("unexpected output: %s", val)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NormalizeContext(ctx, src); err != context.Canceled {
+		t.Errorf("%v != %v", err, context.Canceled)
+	}
+
+	// cancellation is noticed in the middle of a large document, the first
+	// object being sorted cancels the context
+	large := `[{"b": 1, "a": 2}, ` + strings.Repeat(`{"a": [1, 2, 3]}, `, 100000) + `1]`
+	ctx, cancel = context.WithCancel(context.Background())
+	n := New(WithKeyComparator(func(a, b string) bool {
+		cancel()
+		return a < b
+	}))
+	if _, err := n.NormalizeContext(ctx, []byte(large)); err != context.Canceled {
+		t.Errorf("%v != %v", err, context.Canceled)
+	}
+}
+
+func TestNormalizerNormalize(t *testing.T) {
+	n := New()
+	check := func(src string) {
+		expected, expectedErr := Normalize([]byte(src))
+		data, err := n.Normalize([]byte(src))
+		if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+			t.Errorf("%v != %v, src: %s", err, expectedErr, src)
+		} else if val := string(data); val != string(expected) {
+			t.Errorf("%v != %v", val, string(expected))
+		}
+	}
+
+	check(`{"b": 1, "a": "x"}`)
+	check(`[1, {"d": [], "c": null}]`)
+	check(`1 2`)
+}
+
+func TestNormalizeTo(t *testing.T) {
+	check := func(src string) {
+		expected, expectedErr := Normalize([]byte(src))
+
+		var w bytes.Buffer
+		w.WriteString("prefix:")
+		cnt, err := NormalizeTo(&w, []byte(src))
+		if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+			t.Errorf("%v != %v, src: %s", err, expectedErr, src)
+		} else if cnt != len(expected) {
+			t.Errorf("%d != %d, src: %s", cnt, len(expected), src)
+		} else if val := w.String(); val != "prefix:"+string(expected) {
+			t.Errorf("%v != %v", val, "prefix:"+string(expected))
+		}
+	}
+
+	check(`{"b": 1, "a": "x"}`)
+	check(` [1, {"d": [], "c": null}] `)
+	check(`"x"`)
+	check(`[1, x]`)
+}
+
+func TestNormalizePartial(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.NormalizePartial([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q, src: %s", val, expected, src)
+		}
+	}
+
+	n := New()
+	check(n, `{"b": [1, 2], "a": 3}`, `{"a":3,"b":[1,2]}`, nil)
+	check(n, `[1, 2, 3`, `[1,2,3`, ErrUnexpectedEnd)
+	check(n, `[1, 2, `, `[1,2,`, ErrUnexpectedEnd)
+	check(n, `[1, 2, tru`, `[1,2,`, ErrUnexpectedEnd)
+	check(n, `[1.50, "x", {"b": 1, "a": 2}, [3, x]]`, `[1.5,"x",{"a":2,"b":1},[3,`, JsonSyntaxError)
+	check(n, `{"b": 1, "a": {"d": [null, fals`, `{"b":1,"a":{"d":[null,`, ErrUnexpectedEnd)
+	check(n, `{"a": 1 "b": 2}`, `{"a":1`, JsonSyntaxError)
+	check(n, `[1] 2`, `[1]`, ErrTrailingData)
+	check(n, `tru`, ``, ErrUnexpectedEnd)
+	check(New(WithIndent("", " ")), `[1, [2`, "[\n 1,\n [\n  2", ErrUnexpectedEnd)
+
+	if data, err := n.Normalize([]byte(`[1, 2`)); err == nil || data != nil {
+		t.Errorf("%q, %v", data, err)
+	}
+}
+
+func TestNormalizeN(t *testing.T) {
+	check := func(src, expected string, expectedN int, expectedError error) {
+		data, n, err := NormalizeN([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %q", err, expectedError, src)
+		} else if val := string(data); val != expected || n != expectedN {
+			t.Errorf("%v, %d != %v, %d, src: %q", val, n, expected, expectedN, src)
+		}
+	}
+
+	check(`{"b":1,"a":2}`, `{"a":2,"b":1}`, 13, nil)
+	check(`{"b": 1, "a": 2} {"c": 3}`, `{"a":2,"b":1}`, 16, nil)
+	check(`  [1, 2]  tail`, `[1,2]`, 8, nil)
+	check("\n\t\"é\",", `"é"`, 6, nil)
+	check(`12 34`, `12`, 2, nil)
+	check(`12,34`, `12`, 2, nil)
+	check(`1.50`, `1.5`, 4, nil)
+	check(`true false`, `true`, 4, nil)
+	check("\xef\xbb\xbfnull", `null`, 7, nil)
+	check(`[1]]`, `[1]`, 3, nil)
+
+	check(``, ``, 0, ErrEmptyInput)
+	check(`  `, ``, 0, ErrEmptyInput)
+	check(`[1`, ``, 0, ErrUnexpectedEnd)
+	check(`x`, ``, 0, JsonSyntaxError)
+	check(`123x`, ``, 0, JsonSyntaxError)
+	check(`"a"x`, `"a"`, 3, nil)
+	check(`123]x`, `123`, 3, nil)
+
+	src := []byte(`{"a": 1} [2] "x"`)
+	var values []string
+	for len(bytes.TrimSpace(src)) != 0 {
+		data, n, err := NormalizeN(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, string(data))
+		src = src[n:]
+	}
+	if val := strings.Join(values, " "); val != `{"a":1} [2] "x"` {
+		t.Errorf("%v", val)
+	}
+}
+
+func TestNormalizeReader(t *testing.T) {
+	check := func(src, expected string, expectedError error) {
+		var w bytes.Buffer
+		// hide the concrete reader type to go through the buffered path
+		err := NormalizeReader(struct{ io.Reader }{strings.NewReader(src)}, &w)
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := w.String(); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(`{"b": 1, "a": "x"}`, `{"a":"x","b":1}`, nil)
+	check(` [1, 2] `, `[1,2]`, nil)
+	check(`1`, `1`, nil)
+	check(`1 2`, ``, JsonSyntaxError)
+}
+
+func TestNormalizeReaderLargeArray(t *testing.T) {
+	var src bytes.Buffer
+	src.WriteString("[\n")
+	for i := 0; src.Len() < 4<<20; i++ {
+		if i > 0 {
+			src.WriteString(",\n")
+		}
+		fmt.Fprintf(&src, `  {"b": %d, "a": "item %d", "c": [%d, 1.50]}`, i, i, i)
+	}
+	src.WriteString("\n]\n")
+
+	expected, err := Normalize(src.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var w bytes.Buffer
+	r := struct{ io.Reader }{bytes.NewReader(src.Bytes())}
+	if err := NormalizeReader(r, &w); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("streamed output differs from Normalize")
+	}
+	if !bytes.HasPrefix(expected, []byte(`[{"a":"item 0","b":0,"c":[0,1.5]},`)) {
+		t.Errorf("unexpected output prefix: %s", expected[:64])
+	}
+}
+
+// chunkReader hands out its data in chunks of varying size, like a network
+// connection.
+type chunkReader struct {
+	data []byte
+	n    int
+}
+
+func (r *chunkReader) Read(b []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	r.n = r.n%7 + 1
+	size := r.n
+	if size > len(b) {
+		size = len(b)
+	}
+	if size > len(r.data) {
+		size = len(r.data)
+	}
+	copy(b, r.data[:size])
+	r.data = r.data[size:]
+	return size, nil
+}
+
+func TestNormalizeReaderSlow(t *testing.T) {
+	src := "\xef\xbb\xbf {\"b\": [1.50, \"caf\u00e9 😀\", {\"y\": null, \"x\": true}], \"a\": -0.0}\n"
+	expected := "{\"a\":0,\"b\":[1.5,\"café 😀\",{\"x\":true,\"y\":null}]}"
+
+	check := func(name string, r io.Reader) {
+		var w bytes.Buffer
+		if err := NormalizeReader(r, &w); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		} else if val := w.String(); val != expected {
+			t.Errorf("%s: %v != %v", name, val, expected)
+		}
+	}
+
+	check("strings", strings.NewReader(src))
+	check("one byte", iotest.OneByteReader(strings.NewReader(src)))
+	check("half", iotest.HalfReader(strings.NewReader(src)))
+	check("data err", iotest.DataErrReader(strings.NewReader(src)))
+	check("chunks", &chunkReader{data: []byte(src)})
+
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader(`{"a": [1, `), iotest.ErrReader(errRead))
+	if err := NormalizeReader(iotest.OneByteReader(r), io.Discard); !errors.Is(err, errRead) {
+		t.Errorf("%v != %v", err, errRead)
+	}
+
+	values, err := NormalizeStream(&chunkReader{data: []byte(`{"b":1,"a":2} [3, 4]` + "\n" + `"x" 5 `)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val := string(bytes.Join(values, []byte(" "))); val != `{"a":2,"b":1} [3,4] "x" 5` {
+		t.Errorf("unexpected values: %s", val)
+	}
+}
+
+// failingReader hands out its data in small chunks and then fails with err,
+// along with the last chunk when together is set. Reads after the failure
+// report io.EOF, so that a dropped error looks like a clean end.
+type failingReader struct {
+	data     []byte
+	err      error
+	together bool
+	failed   bool
+}
+
+func (r *failingReader) Read(b []byte) (int, error) {
+	if r.failed {
+		return 0, io.EOF
+	}
+	if len(r.data) == 0 {
+		r.failed = true
+		return 0, r.err
+	}
+	size := 3
+	if size > len(b) {
+		size = len(b)
+	}
+	if size > len(r.data) {
+		size = len(r.data)
+	}
+	copy(b, r.data[:size])
+	r.data = r.data[size:]
+	if r.together && len(r.data) == 0 {
+		r.failed = true
+		return size, r.err
+	}
+	return size, nil
+}
+
+func TestReaderErrors(t *testing.T) {
+	errRead := errors.New("read failed")
+	n := New(WithComments(true), WithCaseInsensitiveLiterals(true))
+
+	entryPoints := map[string]func(r io.Reader) error{
+		"NormalizeReader": func(r io.Reader) error {
+			return n.NormalizeReader(r, io.Discard)
+		},
+		"NormalizeStream": func(r io.Reader) error {
+			_, err := n.NormalizeStream(r)
+			return err
+		},
+		"NormalizeLines": func(r io.Reader) error {
+			return n.NormalizeLines(r, io.Discard)
+		},
+		"ParseValue": func(r io.Reader) error {
+			_, err := n.ParseValue(r)
+			return err
+		},
+		"Tokenizer": func(r io.Reader) error {
+			tok := n.NewTokenizer(r)
+			for {
+				if _, err := tok.Next(); err != nil {
+					return err
+				}
+			}
+		},
+		"UTF-16": func(r io.Reader) error {
+			data, _ := io.ReadAll(r)
+			r = &failingReader{data: encode(EncodingUTF16LE, string(data)), err: errRead}
+			return New(WithComments(true), WithCaseInsensitiveLiterals(true), WithInputEncoding(EncodingUTF16LE)).NormalizeReader(r, io.Discard)
+		},
+	}
+
+	for _, src := range []string{
+		``,
+		`  `,
+		`{"a": [1, `,
+		`{"a": "caf`,
+		`{"a": "\u00`,
+		`{"a": 12`,
+		`{"a": 1.5e`,
+		`[tru`,
+		`[N`,
+		`{"ke`,
+		`{"key" `,
+		`[1 /* comm`,
+		`[1 // comm`,
+	} {
+		for name, parse := range entryPoints {
+			for _, together := range []bool{false, true} {
+				err := parse(&failingReader{data: []byte(src), err: errRead, together: together})
+				if !errors.Is(err, errRead) || errors.Is(err, JsonSyntaxError) {
+					t.Errorf("%s: %v != %v, src: %s, together: %v", name, err, errRead, src, together)
+				}
+			}
+		}
+	}
+}
+
+func TestNormalizeConcurrent(t *testing.T) {
+	const goroutines = 64
+
+	srcs := make([][]byte, goroutines)
+	expected := make([]string, goroutines)
+	for i := range srcs {
+		var src, exp strings.Builder
+		src.WriteString(`{"list": [`)
+		exp.WriteString(`{"id":`)
+		fmt.Fprintf(&exp, "%d,", i)
+		exp.WriteString(`"list":[`)
+		for j := 0; j < i; j++ {
+			if j != 0 {
+				src.WriteString(", ")
+				exp.WriteString(",")
+			}
+			fmt.Fprintf(&src, `{"z": "%s", "y": %d.0}`, strings.Repeat("x", j), j)
+			fmt.Fprintf(&exp, `{"y":%d,"z":"%s"}`, j, strings.Repeat("x", j))
+		}
+		fmt.Fprintf(&src, `], "id": %d}`, i)
+		exp.WriteString(`]}`)
+		srcs[i] = []byte(src.String())
+		expected[i] = exp.String()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for k := 0; k < 50; k++ {
+				data, err := Normalize(srcs[i])
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if val := string(data); val != expected[i] {
+					t.Errorf("%v != %v", val, expected[i])
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
 }
 
 func BenchmarkParseNull(b *testing.B) {
 	r := bytes.NewReader([]byte("null"))
+	p := New().newParser(r)
 
 	for i := 0; i < b.N; i++ {
 		r.Seek(0, io.SeekStart)
-		_, err := parseValue(r)
+		_, err := p.parseValue(nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -194,10 +844,11 @@ func BenchmarkParseNull(b *testing.B) {
 
 func BenchmarkParseNumber(b *testing.B) {
 	r := bytes.NewReader([]byte("12345.456"))
+	p := New().newParser(r)
 
 	for i := 0; i < b.N; i++ {
 		r.Seek(0, io.SeekStart)
-		_, err := parseValue(r)
+		_, err := p.parseValue(nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -205,11 +856,28 @@ func BenchmarkParseNumber(b *testing.B) {
 }
 
 func BenchmarkParseString(b *testing.B) {
-	r := bytes.NewReader([]byte(`"abc 123 xyz"`))
+	r := newSliceReader([]byte(`"abc 123 xyz"`))
+	p := New().newParser(r)
 
 	for i := 0; i < b.N; i++ {
-		r.Seek(0, io.SeekStart)
-		_, err := parseValue(r)
+		r.off = 0
+		_, err := p.parseValue(nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseLongString(b *testing.B) {
+	src := []byte(`"` + strings.Repeat("lorem ipsum dolor sit amet, ", 1<<20/28) + `"`)
+	r := newSliceReader(src)
+	p := New().newParser(r)
+
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.off = 0
+		_, err := p.parseValue(nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -218,10 +886,11 @@ func BenchmarkParseString(b *testing.B) {
 
 func BenchmarkParseIntArray(b *testing.B) {
 	r := bytes.NewReader([]byte(`[1, 2, 3, 4, 5]`))
+	p := New().newParser(r)
 
 	for i := 0; i < b.N; i++ {
 		r.Seek(0, io.SeekStart)
-		_, err := parseValue(r)
+		_, err := p.parseValue(nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -230,10 +899,11 @@ func BenchmarkParseIntArray(b *testing.B) {
 
 func BenchmarkParseStringArray(b *testing.B) {
 	r := bytes.NewReader([]byte(`["1", "2", "3", "4", "5"]`))
+	p := New().newParser(r)
 
 	for i := 0; i < b.N; i++ {
 		r.Seek(0, io.SeekStart)
-		_, err := parseValue(r)
+		_, err := p.parseValue(nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -242,16 +912,50 @@ func BenchmarkParseStringArray(b *testing.B) {
 
 func BenchmarkParseObject(b *testing.B) {
 	r := bytes.NewReader([]byte(`{"b": 1, "a": "xyz", "d": {"y": 2, "x": "z"}, "c": [1, 3, 2]}`))
+	p := New().newParser(r)
 
 	for i := 0; i < b.N; i++ {
 		r.Seek(0, io.SeekStart)
-		_, err := parseValue(r)
+		_, err := p.parseValue(nil)
 		if err != nil {
 			b.Fatal(err)
 		}
 	}
 }
 
+func BenchmarkNormalizeReuse(b *testing.B) {
+	src := []byte(`{"b": 1, "a": "xyz", "d": {"y": 2, "x": "z"}, "c": [1, 3, 2]}`)
+	n := New(WithDuplicateKeys(DuplicateKeysError))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := n.Normalize(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNormalizeNew(b *testing.B) {
+	src := []byte(`{"b": 1, "a": "xyz", "d": {"y": 2, "x": "z"}, "c": [1, 3, 2]}`)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := New(WithDuplicateKeys(DuplicateKeysError)).Normalize(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidObject(b *testing.B) {
+	src := []byte(`{"b": 1, "a": "xyz", "d": {"y": 2, "x": "z"}, "c": [1, 3, 2]}`)
+
+	for i := 0; i < b.N; i++ {
+		if !Valid(src) {
+			b.Fatal("invalid")
+		}
+	}
+}
+
 func BenchmarkParseObjectToMap(b *testing.B) {
 	src := []byte(`{"b": 1, "a": "xyz", "d": {"y": 2, "x": "z"}, "c": [1, 3, 2]}`)
 
@@ -266,3 +970,121 @@ func BenchmarkParseObjectToMap(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkParseNested(b *testing.B) {
+	src := strings.Repeat(`{"b": [1, {"c": "xyz"}, `, 32) + `null` + strings.Repeat(`], "a": 2}`, 32)
+	r := bytes.NewReader([]byte(src))
+	p := New().newParser(r)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Seek(0, io.SeekStart)
+		_, err := p.parseValue(nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseDeepArray(b *testing.B) {
+	src := strings.Repeat(`[1, `, 256) + `2` + strings.Repeat(`]`, 256)
+	r := bytes.NewReader([]byte(src))
+	p := New().newParser(r)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Seek(0, io.SeekStart)
+		_, err := p.parseValue(nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNormalizeSorted(b *testing.B) {
+	benchmarkNormalizeWide(b, New())
+}
+
+func BenchmarkNormalizeUnsorted(b *testing.B) {
+	benchmarkNormalizeWide(b, New(WithSortKeys(false)))
+}
+
+func benchmarkNormalizeWide(b *testing.B, n *Normalizer) {
+	src := wideDocument()
+
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := n.Normalize(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNormalizeLargeArray(b *testing.B) {
+	var src bytes.Buffer
+	src.WriteString("[")
+	for i := 0; i < 100000; i++ {
+		if i > 0 {
+			src.WriteString(",")
+		}
+		fmt.Fprintf(&src, `{"id":%d,"v":"x"}`, i)
+	}
+	src.WriteString("]")
+
+	b.SetBytes(int64(src.Len()))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Normalize(src.Bytes()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// wideDocument returns an array of 100 small objects.
+func wideDocument() []byte {
+	var src bytes.Buffer
+	src.WriteString("[")
+	for i := 0; i < 100; i++ {
+		if i > 0 {
+			src.WriteString(", ")
+		}
+		fmt.Fprintf(&src, `{"id": %d, "name": "item %d", "tags": ["a", "b"], "price": 1.50, "meta": {"z": null, "y": true}}`, i, i)
+	}
+	src.WriteString("]")
+	return src.Bytes()
+}
+
+// BenchmarkNormalizeVsJSONRoundTrip compares Normalize with the usual way of
+// getting a canonical form with encoding/json: decoding into generic values
+// and encoding them again, which sorts the keys of maps. Numbers are decoded
+// as json.Number so that they are not rounded.
+func BenchmarkNormalizeVsJSONRoundTrip(b *testing.B) {
+	src := wideDocument()
+
+	b.Run("Normalize", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := Normalize(src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("RoundTrip", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v interface{}
+			dec := json.NewDecoder(bytes.NewReader(src))
+			dec.UseNumber()
+			if err := dec.Decode(&v); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := json.Marshal(v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
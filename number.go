@@ -0,0 +1,167 @@
+package normalizer
+
+import (
+	"strconv"
+)
+
+// maxExponent bounds the magnitude of a number's exponent part, both as
+// written in the input and in the canonical form, so that every canonical
+// number can be parsed again. Numbers outside of this range are rejected as
+// a syntax error.
+const maxExponent = 1000000000
+
+// canonicalNumber converts a JSON number literal into its canonical form.
+//
+// The literal is processed as exact decimal text, without going through
+// float64, so no precision is ever lost. The value is reduced to its
+// significant digits and a decimal exponent, and emitted following the
+// ECMAScript Number.prototype.toString layout, except for integers:
+//
+//   - integral values with at most 21 trailing zeros are written as plain
+//     integers whatever their length (`100.00`, `1e2` and `100` all become
+//     `100`), so that long IDs are emitted byte-for-byte;
+//   - other values whose decimal point falls within the first 21 digits are
+//     written in plain decimal form (`1.50` -> `1.5`, `1e-3` -> `0.001`);
+//   - everything else uses the exponent form `d.ddde+x` / `d.ddde-x`
+//     (`1e22` -> `1e+22`, `0.0000001` -> `1e-7`).
+//
+// Negative zero is normalized to `0`.
+func canonicalNumber(src []byte) ([]byte, error) {
+	i := 0
+	neg := false
+	if i < len(src) && src[i] == '-' {
+		neg = true
+		i++
+	}
+
+	digits := make([]byte, 0, len(src))
+	var exp int64
+
+	start := i
+	for i < len(src) && isDigit(src[i]) {
+		digits = append(digits, src[i])
+		i++
+	}
+	if i == start {
+		return nil, JsonSyntaxError
+	}
+
+	if i < len(src) && src[i] == '.' {
+		i++
+		for i < len(src) && isDigit(src[i]) {
+			digits = append(digits, src[i])
+			exp--
+			i++
+		}
+	}
+
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		i++
+		expNeg := false
+		if i < len(src) && (src[i] == '+' || src[i] == '-') {
+			expNeg = src[i] == '-'
+			i++
+		}
+		start = i
+		var e int64
+		for i < len(src) && isDigit(src[i]) {
+			e = e*10 + int64(src[i]-'0')
+			if e > maxExponent {
+				return nil, JsonSyntaxError
+			}
+			i++
+		}
+		if i == start {
+			return nil, JsonSyntaxError
+		}
+		if expNeg {
+			e = -e
+		}
+		exp += e
+	}
+
+	if i != len(src) {
+		return nil, JsonSyntaxError
+	}
+
+	for len(digits) > 0 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+	for len(digits) > 0 && digits[len(digits)-1] == '0' {
+		digits = digits[:len(digits)-1]
+		exp++
+	}
+	if len(digits) == 0 {
+		return []byte("0"), nil
+	}
+
+	// the exponent of the d.ddde±x form, fraction digits included
+	if x := int64(len(digits)) + exp - 1; x > maxExponent || x < -maxExponent {
+		return nil, JsonSyntaxError
+	}
+
+	if exp >= 0 && exp <= 21 {
+		return appendInteger(nil, neg, digits, int(exp)), nil
+	}
+	return formatDecimal(neg, digits, int(exp)), nil
+}
+
+// appendInteger appends the integer digits * 10^exp, with exp >= 0, to buf.
+func appendInteger(buf []byte, neg bool, digits []byte, exp int) []byte {
+	buf = growBytes(buf, len(digits)+exp+1)
+	if neg {
+		buf = append(buf, '-')
+	}
+	buf = append(buf, digits...)
+	for i := 0; i < exp; i++ {
+		buf = append(buf, '0')
+	}
+	return buf
+}
+
+// formatDecimal writes the value digits * 10^exp, where digits has neither
+// leading nor trailing zeros, with the ECMAScript Number.prototype.toString
+// layout.
+func formatDecimal(neg bool, digits []byte, exp int) []byte {
+	// point is the position of the decimal point relative to the first digit
+	point := len(digits) + exp
+
+	if exp >= 0 && point <= 21 {
+		return appendInteger(nil, neg, digits, exp)
+	}
+
+	buf := make([]byte, 0, len(digits)+8)
+	if neg {
+		buf = append(buf, '-')
+	}
+
+	switch {
+	case 0 < point && point <= 21:
+		buf = append(buf, digits[:point]...)
+		buf = append(buf, '.')
+		buf = append(buf, digits[point:]...)
+	case -6 < point && point <= 0:
+		buf = append(buf, '0', '.')
+		for i := point; i < 0; i++ {
+			buf = append(buf, '0')
+		}
+		buf = append(buf, digits...)
+	default:
+		buf = append(buf, digits[0])
+		if len(digits) > 1 {
+			buf = append(buf, '.')
+			buf = append(buf, digits[1:]...)
+		}
+		buf = append(buf, 'e')
+		if point-1 >= 0 {
+			buf = append(buf, '+')
+		}
+		buf = strconv.AppendInt(buf, int64(point-1), 10)
+	}
+
+	return buf
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
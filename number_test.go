@@ -0,0 +1,141 @@
+package normalizer
+
+import (
+	"testing"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	check := func(expected string, srcs ...string) {
+		for _, src := range srcs {
+			data, err := canonicalNumber([]byte(src))
+			if err != nil {
+				t.Errorf("%v, src: %s", err, src)
+			} else if val := string(data); val != expected {
+				t.Errorf("%v != %v, src: %s", val, expected, src)
+			}
+		}
+	}
+
+	check(`0`, `0`, `-0`, `0.0`, `-0.0`, `0e10`, `0.000e-5`)
+	check(`1`, `1`, `1.0`, `1.000`, `10e-1`, `0.1e1`, `1E0`, `1e+0`)
+	check(`100`, `100`, `100.00`, `1e2`, `1E2`, `1e+2`, `10e1`, `1000e-1`)
+	check(`-100`, `-100`, `-100.0`, `-1e2`)
+	check(`1.5`, `1.5`, `1.50`, `15e-1`, `0.15e1`)
+	check(`0.001`, `0.001`, `1e-3`, `0.00100`)
+	check(`0.000001`, `1e-6`)
+	check(`1e-7`, `1e-7`, `0.0000001`, `10e-8`)
+	check(`123456789012345678901`, `123456789012345678901`, `1.23456789012345678901e20`)
+	check(`1000000000000000000000`, `1e21`, `1000000000000000000000`, `10e20`)
+	check(`1e+22`, `1e22`, `10000000000000000000000`)
+	check(`1234567890123456789012345`, `1234567890123456789012345`, `1.234567890123456789012345e24`)
+	check(`1.2345e+30`, `12345e26`)
+	check(`-2.5e-10`, `-25e-11`)
+}
+
+func TestCanonicalNumberErrors(t *testing.T) {
+	check := func(src string) {
+		if _, err := canonicalNumber([]byte(src)); err != JsonSyntaxError {
+			t.Errorf("%v != %v, src: %s", err, JsonSyntaxError, src)
+		}
+	}
+
+	check(`-`)
+	check(`e5`)
+	check(`1e`)
+	check(`1e+`)
+	check(`1e99999999999`)
+	check(`1e1000000001`)
+	check(`1e2147483648`)
+	check(`1e-2147483648`)
+	check(`1e9223372036854775808`)
+
+	// the exponent of the canonical form is bounded as well
+	check(`10000000000E10000000000`)
+	check(`10e1000000000`)
+	check(`0.01e-1000000000`)
+}
+
+func TestCanonicalNumberExponentBound(t *testing.T) {
+	for _, src := range []string{`1e1000000000`, `1e-1000000000`, `-12345e999999996`, `10e999999999`} {
+		data, err := canonicalNumber([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+			continue
+		}
+		if again, err := canonicalNumber(data); err != nil || string(again) != string(data) {
+			t.Errorf("%s, %v, src: %s", again, err, data)
+		}
+	}
+}
+
+func TestNormalizeLargeIntegers(t *testing.T) {
+	check := func(src, expected string) {
+		data, err := Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	// beyond 2^53 float64 can not represent these exactly
+	check(`9007199254740993`, `9007199254740993`)
+	check(`-9007199254740993`, `-9007199254740993`)
+	check(`18446744073709551615`, `18446744073709551615`)
+	check(`12345678901234567890`, `12345678901234567890`)
+	check(`99999999999999999999`, `99999999999999999999`)
+	check(`-12345678901234567891`, `-12345678901234567891`)
+	check(`{"id": 12345678901234567891, "n": [98765432109876543219]}`,
+		`{"id":12345678901234567891,"n":[98765432109876543219]}`)
+
+	// larger integers too
+	check(`1234567890123456789012345`, `1234567890123456789012345`)
+	check(`-123456789012345678901234567890123456789`, `-123456789012345678901234567890123456789`)
+	check(`1e30`, `1e+30`)
+	check(`12345678901234567890.5`, `12345678901234567890.5`)
+}
+
+func TestNormalizeNegativeZero(t *testing.T) {
+	check := func(src, expected string) {
+		data, err := Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(`0`, `0`)
+	check(`-0`, `0`)
+	check(`-0.0`, `0`)
+	check(`-0.000`, `0`)
+	check(`-0e10`, `0`)
+	check(`-0E-3`, `0`)
+	check(`[-0, 0, -0.0]`, `[0,0,0]`)
+	check(`{"a": -0}`, `{"a":0}`)
+}
+
+func TestNormalizeIntegralNumbers(t *testing.T) {
+	check := func(src, expected string) {
+		data, err := Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(`2.0`, `2`)
+	check(`2.00`, `2`)
+	check(`2e0`, `2`)
+	check(`20e-1`, `2`)
+	check(`0.2e1`, `2`)
+	check(`1e3`, `1000`)
+	check(`1.5e3`, `1500`)
+	check(`-3.000e2`, `-300`)
+	check(`2.5`, `2.5`)
+	check(`2.50`, `2.5`)
+	check(`25e-1`, `2.5`)
+	check(`[1, 1.0, 1e0, 10e-1]`, `[1,1,1,1]`)
+	check(`{"int": 7, "float": 7.0}`, `{"float":7,"int":7}`)
+}
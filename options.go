@@ -0,0 +1,495 @@
+package normalizer
+
+// WithSortKeys controls whether object keys are sorted. When disabled the keys
+// are emitted in the order they appear in the input. Enabled by default.
+func WithSortKeys(sort bool) Option {
+	return func(n *Normalizer) {
+		n.sortKeys = sort
+	}
+}
+
+// DuplicateKeyPolicy defines how repeated keys of an object are handled.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysKeepAll emits every member, including repeated keys.
+	DuplicateKeysKeepAll DuplicateKeyPolicy = iota
+	// DuplicateKeysError fails with ErrDuplicateKey.
+	DuplicateKeysError
+	// DuplicateKeysKeepFirst keeps the first value of a repeated key.
+	DuplicateKeysKeepFirst
+	// DuplicateKeysKeepLast keeps the last value of a repeated key. Like
+	// JavaScript's JSON.parse, the member keeps the position of the first
+	// occurrence when keys are not sorted.
+	DuplicateKeysKeepLast
+)
+
+// WithDuplicateKeys sets the policy for repeated object keys. Defaults to
+// DuplicateKeysKeepAll.
+func WithDuplicateKeys(policy DuplicateKeyPolicy) Option {
+	return func(n *Normalizer) {
+		n.duplicateKeys = policy
+	}
+}
+
+// WithMaxDepth limits the nesting of objects and arrays. Deeper documents fail
+// with ErrMaxDepthExceeded. A value of 0 or less disables the limit. Defaults
+// to DefaultMaxDepth.
+func WithMaxDepth(depth int) Option {
+	return func(n *Normalizer) {
+		n.maxDepth = depth
+	}
+}
+
+// WithReplaceInvalidUTF8 controls the handling of malformed UTF-8 in strings.
+// By default it fails with ErrInvalidUTF8, when enabled each invalid byte is
+// replaced with U+FFFD.
+func WithReplaceInvalidUTF8(replace bool) Option {
+	return func(n *Normalizer) {
+		n.replaceInvalidUTF8 = replace
+	}
+}
+
+// WithIndent enables pretty printed output similar to json.MarshalIndent:
+// each member of an object or array starts on a new line beginning with prefix
+// followed by one copy of indent per nesting level. Keys are still sorted.
+func WithIndent(prefix, indent string) Option {
+	return func(n *Normalizer) {
+		n.pretty = true
+		n.prefix = prefix
+		n.indent = indent
+	}
+}
+
+// WithTopLevelArrayPerLine puts each element of a top-level array on a line
+// of its own, while the elements themselves stay compact:
+//
+//	[
+//	{"a":1,"b":[1,2]},
+//	{"a":2,"b":[]}
+//	]
+//
+// This suits arrays of log records. It has no effect with WithIndent or
+// WithJCS. Disabled by default.
+func WithTopLevelArrayPerLine(perLine bool) Option {
+	return func(n *Normalizer) {
+		n.topLevelArrayPerLine = perLine
+	}
+}
+
+// WithSeparators sets the separators written between the members of objects
+// and arrays and between keys and values, e.g. ", " and ": " for the output
+// of Python's json.dumps. They replace the "," and ":" of the compact output
+// and the ": " of WithIndent, and should only add white space to keep the
+// output valid JSON. WithJCS ignores them.
+func WithSeparators(itemSeparator, keySeparator string) Option {
+	return func(n *Normalizer) {
+		n.separators = true
+		n.itemSeparator = itemSeparator
+		n.keySeparator = keySeparator
+	}
+}
+
+// WithAllowMultipleValues accepts a stream of top-level values, like
+// `{"a":1} {"b":2}`, where a single value and filler symbols are required
+// otherwise. The values are normalized one by one and separated by newlines
+// in the output. A number has to be followed by a filler symbol before the
+// next value. Disabled by default, trailing data is then reported with
+// ErrTrailingData.
+func WithAllowMultipleValues(allow bool) Option {
+	return func(n *Normalizer) {
+		n.multipleValues = allow
+	}
+}
+
+// WithAllowedTopLevel restricts the kinds of top-level values, e.g. an API
+// taking objects only uses WithAllowedTopLevel(KindObject). Other values are
+// rejected with a TopLevelError before they are parsed. Without kinds every
+// value is allowed, which is the default.
+func WithAllowedTopLevel(kinds ...Kind) Option {
+	return func(n *Normalizer) {
+		n.allowedTopLevel = 0
+		for _, kind := range kinds {
+			n.allowedTopLevel |= 1 << uint(kind)
+		}
+	}
+}
+
+// WithTrailingNewline ends the normalized document with a newline, as text
+// files usually do. The newline is added once, re-normalizing the output
+// gives the same bytes. NormalizeLines ends every line with a single newline
+// either way. Disabled by default.
+func WithTrailingNewline(newline bool) Option {
+	return func(n *Normalizer) {
+		n.trailingNewline = newline
+	}
+}
+
+// WithComments enables a lenient mode accepting // line and /* block */
+// comments wherever filler symbols are allowed. Comments are dropped from the
+// output. Disabled by default.
+func WithComments(comments bool) Option {
+	return func(n *Normalizer) {
+		n.comments = comments
+	}
+}
+
+// WithTrailingCommas enables a lenient mode accepting a comma right before the
+// closing bracket of an array or object. The comma is dropped from the output.
+// Disabled by default.
+func WithTrailingCommas(trailingCommas bool) Option {
+	return func(n *Normalizer) {
+		n.trailingCommas = trailingCommas
+	}
+}
+
+// WithCaseInsensitiveSort sorts object keys ignoring letter case, keys which
+// only differ in case are ordered byte-wise. The original key casing is kept
+// in the output. Disabled by default.
+func WithCaseInsensitiveSort(caseInsensitive bool) Option {
+	return func(n *Normalizer) {
+		n.caseInsensitiveSort = caseInsensitive
+	}
+}
+
+// WithKeyComparator replaces the ordering of object keys with less, which is
+// given the decoded keys. It takes precedence over WithCaseInsensitiveSort.
+// When nil the default byte-wise ordering applies.
+func WithKeyComparator(less func(a, b string) bool) Option {
+	return func(n *Normalizer) {
+		n.keyComparator = less
+	}
+}
+
+// WithSkipBlankLines controls whether NormalizeLines skips blank lines or
+// reports them as a syntax error. Enabled by default.
+func WithSkipBlankLines(skip bool) Option {
+	return func(n *Normalizer) {
+		n.skipBlankLines = skip
+	}
+}
+
+// WithJCS enables the JSON Canonicalization Scheme of RFC 8785. Numbers are
+// serialized as IEEE 754 doubles the way ECMAScript does, object keys are
+// sorted by their UTF-16 code units and duplicate keys are rejected with
+// ErrDuplicateKey. The mode takes precedence over the key ordering, duplicate
+// key and indentation options, and strings are escaped as RFC 8785 requires,
+// whatever WithASCIIOnly and WithEscapeHTML say. Disabled by default.
+func WithJCS(jcs bool) Option {
+	return func(n *Normalizer) {
+		n.jcs = jcs
+	}
+}
+
+// WithNaturalKeySort orders object keys naturally: runs of digits compare by
+// their numeric value, so that "item2" sorts before "item10" and "2" before
+// "10". WithUTF16KeySort and WithKeyComparator take precedence over it,
+// WithCaseInsensitiveSort applies on top of it. Disabled by default.
+func WithNaturalKeySort(natural bool) Option {
+	return func(n *Normalizer) {
+		n.naturalKeySort = natural
+	}
+}
+
+// WithSortDescending reverses the order of sorted object keys, whichever
+// ordering is in effect: the default byte-wise one, WithCaseInsensitiveSort,
+// WithNaturalKeySort, WithUTF16KeySort or WithKeyComparator. Members with
+// the same key keep their input order. It has no effect with WithJCS, which
+// mandates ascending keys, or when keys are not sorted. Disabled by default.
+func WithSortDescending(descending bool) Option {
+	return func(n *Normalizer) {
+		n.sortDescending = descending
+	}
+}
+
+// WithUTF16KeySort orders object keys by their UTF-16 code units instead of
+// bytes, which only makes a difference for keys containing characters outside
+// of the Basic Multilingual Plane, such as emoji. It takes precedence over
+// WithCaseInsensitiveSort. Disabled by default.
+func WithUTF16KeySort(utf16 bool) Option {
+	return func(n *Normalizer) {
+		n.utf16KeySort = utf16
+	}
+}
+
+// WithOmitNulls drops object members whose value is null. Array elements are
+// kept. Disabled by default.
+func WithOmitNulls(omit bool) Option {
+	return func(n *Normalizer) {
+		n.omitNulls = omit
+	}
+}
+
+// WithOmitEmpty drops empty objects and arrays nested in the document, both as
+// object members and as array elements. Containers which become empty this way
+// are dropped as well, only an empty top-level value is kept. Disabled by
+// default.
+func WithOmitEmpty(omit bool) Option {
+	return func(n *Normalizer) {
+		n.omitEmpty = omit
+	}
+}
+
+// WithMaxNumberLength limits the number of characters of a number literal.
+// Longer numbers fail with ErrTokenTooLong. A value of 0 or less disables the
+// limit, which is the default.
+func WithMaxNumberLength(length int) Option {
+	return func(n *Normalizer) {
+		n.maxNumberLength = length
+	}
+}
+
+// WithMaxStringLength limits the length in bytes of decoded strings, keys
+// included. Longer strings fail with ErrTokenTooLong. A value of 0 or less
+// disables the limit, which is the default.
+func WithMaxStringLength(length int) Option {
+	return func(n *Normalizer) {
+		n.maxStringLength = length
+	}
+}
+
+// WithMaxOutputSize limits the size in bytes of the normalized output, which
+// may be larger than the input, e.g. with WithASCIIOnly or WithIndent. Parsing
+// stops with ErrOutputTooLarge as soon as a value takes the output past the
+// limit. Valid does not build any output and ignores it. A value of 0 or less
+// disables the limit, which is the default.
+func WithMaxOutputSize(size int) Option {
+	return func(n *Normalizer) {
+		n.maxOutputSize = size
+	}
+}
+
+// WithMaxTotalNodes limits the number of values and object keys of a
+// document, counted at every nesting level, so that a shallow document with a
+// huge number of members fails early with ErrMaxNodesExceeded. Every value of
+// NormalizeStream is counted separately. A value of 0 or less disables the
+// limit, which is the default.
+func WithMaxTotalNodes(nodes int) Option {
+	return func(n *Normalizer) {
+		n.maxTotalNodes = nodes
+	}
+}
+
+// WithASCIIOnly escapes every non-ASCII character of strings and keys as
+// \uXXXX, using a surrogate pair for characters outside of the Basic
+// Multilingual Plane, so that the output is plain ASCII. WithJCS ignores it.
+// Disabled by default.
+func WithASCIIOnly(asciiOnly bool) Option {
+	return func(n *Normalizer) {
+		n.asciiOnly = asciiOnly
+	}
+}
+
+// WithEscapeHTML escapes '<', '>' and '&' in strings and keys as \u003c,
+// \u003e and \u0026, like encoding/json does, so that the output can be safely
+// embedded in HTML. WithJCS ignores it. Disabled by default to keep the output
+// compact.
+func WithEscapeHTML(escapeHTML bool) Option {
+	return func(n *Normalizer) {
+		n.escapeHTML = escapeHTML
+	}
+}
+
+// WithSortArrays sorts array elements by their normalized text, so that
+// arrays used as sets normalize identically regardless of the element order.
+// Disabled by default since the order of array elements is significant in
+// general.
+func WithSortArrays(sortArrays bool) Option {
+	return func(n *Normalizer) {
+		n.sortArrays = sortArrays
+	}
+}
+
+// WithDedupArrays drops array elements whose normalized text already
+// appeared in the same array, keeping the first of them. Combined with
+// WithSortArrays the elements are sorted first, so the output is the same for
+// arrays holding the same set of values.
+func WithDedupArrays(dedupArrays bool) Option {
+	return func(n *Normalizer) {
+		n.dedupArrays = dedupArrays
+	}
+}
+
+// WithStripBOM controls whether a UTF-8 byte order mark at the start of the
+// input is skipped. Enabled by default; when disabled the mark is a syntax
+// error like any other unexpected byte.
+func WithStripBOM(stripBOM bool) Option {
+	return func(n *Normalizer) {
+		n.stripBOM = stripBOM
+	}
+}
+
+// WithInputEncoding sets the character encoding of the input, which is
+// transcoded to UTF-8 before parsing. The output is always UTF-8. A byte
+// order mark is handled like a UTF-8 one, see WithStripBOM. Invalid code
+// units, like unpaired surrogates, are treated as malformed UTF-8. Positions
+// of syntax errors count bytes of the transcoded input, NormalizeN still
+// returns the number of input bytes consumed. Defaults to EncodingUTF8.
+func WithInputEncoding(enc Encoding) Option {
+	return func(n *Normalizer) {
+		n.inputEncoding = enc
+	}
+}
+
+// WithSingleQuotes enables a lenient mode accepting keys and strings
+// delimited by single quotes, as written by JavaScript, as well as the \'
+// escape sequence. They are re-quoted with double quotes in the output.
+// Disabled by default.
+func WithSingleQuotes(singleQuotes bool) Option {
+	return func(n *Normalizer) {
+		n.singleQuotes = singleQuotes
+	}
+}
+
+// WithUnquotedKeys enables a lenient mode accepting object keys written as
+// identifiers without quotes, like {a: 1}. An identifier consists of ASCII
+// letters, digits, '_' and '$' and does not start with a digit. The keys are
+// quoted in the output. Disabled by default.
+func WithUnquotedKeys(unquotedKeys bool) Option {
+	return func(n *Normalizer) {
+		n.unquotedKeys = unquotedKeys
+	}
+}
+
+// WithUnicodeWhitespace enables a lenient mode skipping the white space
+// accepted by JavaScript between tokens: vertical tab, form feed, no-break
+// space, byte order mark, the line and paragraph separators and the other
+// Unicode space separators. Only space, tab, line feed and carriage return
+// are skipped otherwise. Disabled by default.
+func WithUnicodeWhitespace(unicodeWhitespace bool) Option {
+	return func(n *Normalizer) {
+		n.unicodeWhitespace = unicodeWhitespace
+	}
+}
+
+// WithCaseInsensitiveLiterals enables a lenient mode accepting the true, false
+// and null literals in any case, like True, FALSE or Null, as emitted by
+// sloppy producers. They are lowercased in the output. NaN and Infinity, if
+// allowed, are still matched exactly. Disabled by default.
+func WithCaseInsensitiveLiterals(caseInsensitive bool) Option {
+	return func(n *Normalizer) {
+		n.caseInsensitiveLit = caseInsensitive
+	}
+}
+
+// WithAllowNonFiniteNumbers enables a lenient mode accepting the NaN,
+// Infinity and -Infinity literals emitted by JavaScript and Python. They are
+// kept as is in the output unless WithNonFiniteAsNull is given. Disabled by
+// default.
+func WithAllowNonFiniteNumbers(allow bool) Option {
+	return func(n *Normalizer) {
+		n.allowNonFinite = allow
+	}
+}
+
+// WithNonFiniteAsNull replaces the non-finite numbers accepted with
+// WithAllowNonFiniteNumbers with null, so that the output is valid JSON.
+func WithNonFiniteAsNull(asNull bool) Option {
+	return func(n *Normalizer) {
+		n.nonFiniteAsNull = asNull
+	}
+}
+
+// WithKeyNFC applies the Unicode normalization form NFC to every decoded
+// object key before keys are compared, sorted and emitted, so that keys which
+// only differ in their normalization form, like a composed and a decomposed
+// "é", sort alike and are merged. Keys which become equal are duplicates and
+// handled according to WithDuplicateKeys: with the default
+// DuplicateKeysKeepAll both members are kept under the same key. It applies
+// before WithKeyTransformer. Disabled by default.
+func WithKeyNFC(nfc bool) Option {
+	return func(n *Normalizer) {
+		n.keyNFC = nfc
+	}
+}
+
+// WithKeyTransformer applies fn to every decoded object key before keys are
+// compared, sorted and emitted, e.g. to lowercase them, an error returned by
+// fn aborts normalization. It applies after WithKeyNFC, which covers Unicode
+// normalization. Keys which become equal are duplicates and handled according
+// to WithDuplicateKeys.
+func WithKeyTransformer(fn func(key string) (string, error)) Option {
+	return func(n *Normalizer) {
+		n.keyTransformer = fn
+	}
+}
+
+// WithValueNFC applies the Unicode normalization form NFC to the decoded
+// content of every string value, but not to keys, so that strings which only
+// differ in their normalization form, like a composed and a decomposed "é",
+// compare and hash equally. Keys are covered by WithKeyNFC. Disabled by
+// default.
+func WithValueNFC(nfc bool) Option {
+	return func(n *Normalizer) {
+		n.valueNFC = nfc
+	}
+}
+
+// WithStringTransformer applies fn to the decoded content of every string
+// value, but not to keys, before it is emitted, e.g. to trim or lowercase
+// strings for comparison. An error returned by fn aborts normalization. It
+// applies after WithValueNFC, which covers Unicode normalization.
+func WithStringTransformer(fn func(decoded string) (string, error)) Option {
+	return func(n *Normalizer) {
+		n.stringTransformer = fn
+	}
+}
+
+// WithTrimStringValues removes the leading and trailing white space, as
+// defined by Unicode, from the decoded content of every string value. Keys
+// and the white space within strings are kept. It applies before
+// WithStringTransformer. Disabled by default.
+func WithTrimStringValues(trim bool) Option {
+	return func(n *Normalizer) {
+		n.trimStringValues = trim
+	}
+}
+
+// WithRecurseEmbeddedJSON normalizes JSON stored in string values, like
+// {"data":"{\"b\":2,\"a\":1}"}, which becomes {"data":"{\"a\":1,\"b\":2}"}.
+// Only strings holding an object or an array are taken for JSON, they are
+// normalized with the same settings, but compact, and embedded again. Other
+// strings, including those failing to normalize, are left untouched. It
+// applies after WithStringTransformer. Disabled by default.
+func WithRecurseEmbeddedJSON(recurse bool) Option {
+	return func(n *Normalizer) {
+		n.recurseEmbedded = recurse
+	}
+}
+
+// WithNumberFormatter replaces the canonical form of numbers with the output
+// of fn, which is written as is. fn receives the number literal as it appears
+// in the input, e.g. to round amounts to a fixed number of decimals, and must
+// not keep it. An error returned by fn aborts normalization. WithJCS takes
+// precedence over it.
+func WithNumberFormatter(fn func(raw []byte) ([]byte, error)) Option {
+	return func(n *Normalizer) {
+		n.numberFormatter = fn
+	}
+}
+
+// WithRejectDuplicateKeys is a shorthand for WithDuplicateKeys, when enabled
+// repeated keys fail with a DuplicateKeyError naming the key, when disabled
+// the default DuplicateKeysKeepAll policy is restored. Rejecting duplicates
+// is the safe choice when the input is also read by other parsers, which may
+// disagree on the value they pick.
+func WithRejectDuplicateKeys(reject bool) Option {
+	return func(n *Normalizer) {
+		if reject {
+			n.duplicateKeys = DuplicateKeysError
+		} else {
+			n.duplicateKeys = DuplicateKeysKeepAll
+		}
+	}
+}
+
+// WithCanonicalVersion pins the canonical form to version, as returned by
+// CanonicalVersion. When the rules of a later release produce a different
+// version, normalization fails with ErrUnsupportedVersion rather than
+// silently producing output which no longer matches stored hashes. An empty
+// version accepts any, which is the default.
+func WithCanonicalVersion(version string) Option {
+	return func(n *Normalizer) {
+		n.version = version
+	}
+}
@@ -0,0 +1,1332 @@
+package normalizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWithSortKeys(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	sorted := New(WithSortKeys(true))
+	unsorted := New(WithSortKeys(false))
+
+	check(sorted, `{"c": 1, "a": 2, "b": 3}`, `{"a":2,"b":3,"c":1}`)
+	check(unsorted, `{"c": 1, "a": 2, "b": 3}`, `{"c":1,"a":2,"b":3}`)
+	check(unsorted, `{"z": {"y": 1, "x": [{"b": 1, "a": 2}]}, "a": null}`, `{"z":{"y":1,"x":[{"b":1,"a":2}]},"a":null}`)
+	check(unsorted, `{"a":1,"b":2}`, `{"a":1,"b":2}`)
+
+	// keys are streamed without being kept
+	check(unsorted, `{"b\u00e9": "x", "": {}, "a\n": [{"d": 1, "c": {"f": 2, "e": 3}}]}`, `{"bé":"x","":{},"a\n":[{"d":1,"c":{"f":2,"e":3}}]}`)
+	check(unsorted, `{"b": 1, "b": 2}`, `{"b":1,"b":2}`)
+	check(New(WithSortKeys(false), WithOmitNulls(true), WithOmitEmpty(true)), `{"c": null, "b": {}, "a": 1, "d": null}`, `{"a":1}`)
+	check(New(WithSortKeys(false), WithIndent("", " ")), `{"b": 1, "a": {"d": 2, "c": 3}}`, "{\n \"b\": 1,\n \"a\": {\n  \"d\": 2,\n  \"c\": 3\n }\n}")
+	check(New(WithSortKeys(false), WithUnquotedKeys(true)), `{b: 1, a: {d: 2}}`, `{"b":1,"a":{"d":2}}`)
+	if !unsorted.Valid([]byte(`{"b": {"d": 2}, "a": 1}`)) || unsorted.Valid([]byte(`{"b": {"d" 2}}`)) {
+		t.Error("unexpected validation result")
+	}
+}
+
+func TestWithDuplicateKeys(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	src := `{"a":1,"a":2,"b":3}`
+
+	check(New(), src, `{"a":1,"a":2,"b":3}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepAll)), src, `{"a":1,"a":2,"b":3}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysError)), src, ``, ErrDuplicateKey)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepFirst)), src, `{"a":1,"b":3}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepLast)), src, `{"a":2,"b":3}`, nil)
+
+	check(New(WithDuplicateKeys(DuplicateKeysError)), `{"a":1,"b":{"a":2}}`, `{"a":1,"b":{"a":2}}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepLast), WithSortKeys(false)),
+		`{"b":1,"a":2,"b":3}`, `{"b":3,"a":2}`, nil)
+
+	// keys are compared decoded, whatever their escaping
+	escaped := `{"a\u0062": 1, "ab": 2, "\u0061b": 3, "c": 4}`
+	check(New(WithDuplicateKeys(DuplicateKeysError)), escaped, ``, ErrDuplicateKey)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepFirst)), escaped, `{"ab":1,"c":4}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepLast)), escaped, `{"ab":3,"c":4}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepLast), WithSortKeys(false)), escaped, `{"ab":3,"c":4}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepFirst)), `{"\u00e9": 1, "é": 2, "\/": 3, "/": 4}`, `{"/":3,"é":1}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepFirst)), `{"\ud83d\ude00": 1, "😀": 2}`, `{"😀":1}`, nil)
+	check(New(WithDuplicateKeys(DuplicateKeysKeepFirst), WithSingleQuotes(true)), `{'a"': 1, "a\"": 2}`, `{"a\"":1}`, nil)
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	check := func(n *Normalizer, src string, expectedError error) {
+		if _, err := n.Normalize([]byte(src)); !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		}
+	}
+
+	nested := func(depth int) string {
+		return strings.Repeat(`[{"a":`, depth/2) + strings.Repeat(`[`, depth%2) +
+			`1` + strings.Repeat(`]`, depth%2) + strings.Repeat(`}]`, depth/2)
+	}
+
+	limited := New(WithMaxDepth(5))
+	check(limited, nested(4), nil)
+	check(limited, nested(5), nil)
+	check(limited, nested(6), ErrMaxDepthExceeded)
+	check(limited, `[[[], {}, [[{}]]]]`, nil)
+	check(limited, `[[[], {}, [[{"a":[]}]]]]`, ErrMaxDepthExceeded)
+
+	check(New(), nested(DefaultMaxDepth), nil)
+	check(New(), nested(DefaultMaxDepth+1), ErrMaxDepthExceeded)
+	check(New(), strings.Repeat(`[`, 1000000), ErrMaxDepthExceeded)
+	check(New(WithMaxDepth(0)), nested(DefaultMaxDepth+1), nil)
+}
+
+func TestWithReplaceInvalidUTF8(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %q", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	strict := New()
+	check(strict, "\"a\xffb\"", ``, ErrInvalidUTF8)
+	check(strict, "\"\xc3\"", ``, ErrInvalidUTF8)
+	check(strict, "\"\xe4\xb8\"", ``, ErrInvalidUTF8)
+	check(strict, "{\"\xed\xa0\x80\": 1}", ``, ErrInvalidUTF8)
+	check(strict, "\"\xef\xbf\xbd\"", "\"\xef\xbf\xbd\"", nil)
+	check(strict, "\"é\"", "\"é\"", nil)
+
+	replace := New(WithReplaceInvalidUTF8(true))
+	check(replace, "\"a\xffb\"", "\"a�b\"", nil)
+	check(replace, "[\"\xe4\xb8\"]", "[\"��\"]", nil)
+}
+
+func TestWithIndent(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	src := `{"b": [1, {"y": null, "x": true}, []], "a": "x", "c": {}}`
+
+	check(New(WithIndent("", "  ")), src, `{
+  "a": "x",
+  "b": [
+    1,
+    {
+      "x": true,
+      "y": null
+    },
+    []
+  ],
+  "c": {}
+}`)
+	check(New(WithIndent("//", "\t")), `[1, {"a": 2}]`, "[\n//\t1,\n//\t{\n//\t\t\"a\": 2\n//\t}\n//]")
+	check(New(WithIndent("", "  ")), `1`, `1`)
+	check(New(WithIndent("", "  ")), `[]`, `[]`)
+
+	// the output matches encoding/json indentation of the compact form
+	compact, err := Normalize([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected bytes.Buffer
+	if err := json.Indent(&expected, compact, "> ", "    "); err != nil {
+		t.Fatal(err)
+	}
+	check(New(WithIndent("> ", "    ")), src, expected.String())
+}
+
+func TestWithTopLevelArrayPerLine(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	n := New(WithTopLevelArrayPerLine(true))
+	check(n, `[{"b": [1, 2], "a": 1}, {"a": 2, "b": []}]`, "[\n{\"a\":1,\"b\":[1,2]},\n{\"a\":2,\"b\":[]}\n]")
+	check(n, `[1, [2, [3]], {"a": [4, 5]}]`, "[\n1,\n[2,[3]],\n{\"a\":[4,5]}\n]")
+	check(n, `[1]`, "[\n1\n]")
+	check(n, `[]`, `[]`)
+	check(n, `{"a": [1, 2], "b": {"c": [3]}}`, `{"a":[1,2],"b":{"c":[3]}}`)
+	check(n, `"x"`, `"x"`)
+
+	check(New(WithTopLevelArrayPerLine(true), WithSortArrays(true)), `[3, [2, 1], 1]`, "[\n1,\n3,\n[1,2]\n]")
+	check(New(WithTopLevelArrayPerLine(true), WithDedupArrays(true)), `[1, 2, 1]`, "[\n1,\n2\n]")
+	check(New(WithTopLevelArrayPerLine(true), WithOmitEmpty(true)), `[{}, 1, []]`, "[\n1\n]")
+	check(New(WithTopLevelArrayPerLine(true), WithAllowMultipleValues(true)), `[1, 2] [3]`, "[\n1,\n2\n]\n[\n3\n]")
+
+	// no effect when pretty printing or in JCS mode
+	check(New(WithTopLevelArrayPerLine(true), WithIndent("", " ")), `[1, [2]]`, "[\n 1,\n [\n  2\n ]\n]")
+	check(New(WithTopLevelArrayPerLine(true), WithJCS(true)), `[1, [2]]`, `[1,[2]]`)
+	check(New(), `[1, [2]]`, `[1,[2]]`)
+}
+
+func TestWithSeparators(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	// json.dumps(..., sort_keys=True)
+	python := New(WithSeparators(", ", ": "))
+	check(python, `{"b":[1,{"y":null,"x":true},[]],"a":"x","c":{}}`, `{"a": "x", "b": [1, {"x": true, "y": null}, []], "c": {}}`)
+	check(python, `{"a":1}`, `{"a": 1}`)
+	check(python, `[1]`, `[1]`)
+	check(python, `[]`, `[]`)
+	check(python, `"a, b: c"`, `"a, b: c"`)
+
+	check(New(WithSeparators(", ", ": "), WithOmitNulls(true)), `{"c":3,"b":null,"a":1}`, `{"a": 1, "c": 3}`)
+	check(New(WithSeparators(", ", ": "), WithSortArrays(true), WithDedupArrays(true)), `[3,1,3,2]`, `[1, 2, 3]`)
+	check(New(WithSeparators(",", " : ")), `{"b":1,"a":2}`, `{"a" : 2,"b" : 1}`)
+
+	// json.dumps(..., indent=2, sort_keys=True)
+	check(New(WithIndent("", "  "), WithSeparators(",", ": ")), `{"b":[1,2],"a":"x"}`, "{\n  \"a\": \"x\",\n  \"b\": [\n    1,\n    2\n  ]\n}")
+	check(New(WithSeparators(", ", ": "), WithJCS(true)), `{"b":[1,2],"a":"x"}`, `{"a":"x","b":[1,2]}`)
+}
+
+func TestWithAllowMultipleValues(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	strict := New()
+	check(strict, `1 2`, ``, ErrTrailingData)
+	check(strict, `{"a":1} {"b":2}`, ``, ErrTrailingData)
+	check(strict, ` 1 `, `1`, nil)
+	check(New(WithAllowMultipleValues(false)), `1 2`, ``, ErrTrailingData)
+
+	n := New(WithAllowMultipleValues(true))
+	check(n, `1 2`, "1\n2", nil)
+	check(n, ` 1 `, `1`, nil)
+	check(n, `{"b":1,"a":2}{"c":[3.0]}`+"\n\n"+`"x"null`, "{\"a\":2,\"b\":1}\n{\"c\":[3]}\n\"x\"\nnull", nil)
+	check(n, `1 x`, ``, JsonSyntaxError)
+	check(n, `1 [`, ``, ErrUnexpectedEnd)
+	check(n, ``, ``, ErrEmptyInput)
+	check(New(WithAllowMultipleValues(true), WithTrailingNewline(true)), `1 2`, "1\n2\n", nil)
+	check(New(WithAllowMultipleValues(true), WithIndent("", " ")), `[1] [2]`, "[\n 1\n]\n[\n 2\n]", nil)
+
+	if !n.Valid([]byte(`1 2`)) || strict.Valid([]byte(`1 2`)) {
+		t.Error("unexpected validation result")
+	}
+}
+
+func TestWithAllowedTopLevel(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	objects := New(WithAllowedTopLevel(KindObject))
+	check(objects, ` {"b": 1, "a": [2]}`, `{"a":[2],"b":1}`, nil)
+	check(objects, `42`, ``, ErrTopLevelNotAllowed)
+	check(objects, `[{}]`, ``, ErrTopLevelNotAllowed)
+	check(objects, `"x"`, ``, ErrTopLevelNotAllowed)
+	check(objects, `null`, ``, ErrTopLevelNotAllowed)
+	check(objects, `x`, ``, JsonSyntaxError)
+	check(objects, ``, ``, ErrEmptyInput)
+
+	containers := New(WithAllowedTopLevel(KindObject, KindArray))
+	check(containers, `[1, "a"]`, `[1,"a"]`, nil)
+	check(containers, `{}`, `{}`, nil)
+	check(containers, `-1`, ``, ErrTopLevelNotAllowed)
+	check(containers, `true`, ``, ErrTopLevelNotAllowed)
+	check(New(WithAllowedTopLevel()), `42`, `42`, nil)
+	check(New(WithAllowedTopLevel(KindNumber), WithAllowNonFiniteNumbers(true)), `NaN`, `NaN`, nil)
+	check(New(WithAllowedTopLevel(KindObject), WithAllowMultipleValues(true)), `{} 1`, ``, ErrTopLevelNotAllowed)
+
+	_, err := objects.Normalize([]byte(`42`))
+	var terr *TopLevelError
+	if !errors.As(err, &terr) || terr.Kind != KindNumber {
+		t.Errorf("unexpected error: %v", err)
+	} else if val := err.Error(); val != "Top-level number not allowed" {
+		t.Errorf("unexpected message: %s", val)
+	}
+	if objects.Valid([]byte(`[]`)) {
+		t.Error("array accepted")
+	}
+	if _, err := objects.ParseValue(strings.NewReader(`[1]`)); !errors.Is(err, ErrTopLevelNotAllowed) {
+		t.Errorf("%v != %v", err, ErrTopLevelNotAllowed)
+	}
+}
+
+func TestWithTrailingNewline(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	n := New(WithTrailingNewline(true))
+	check(n, `{"b": 1, "a": 2}`, "{\"a\":2,\"b\":1}\n")
+	check(n, "[1]\n", "[1]\n")
+	check(n, "[1]\n\n\r\n", "[1]\n")
+	check(n, `"x\n"`, "\"x\\n\"\n")
+	check(New(WithTrailingNewline(true), WithIndent("", "  ")), `[1]`, "[\n  1\n]\n")
+	check(New(), "[1]\n", "[1]")
+
+	first, err := n.Normalize([]byte(`{"a": [1, 2]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := n.Normalize(first)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(first, second) {
+		t.Errorf("%q != %q", second, first)
+	}
+
+	var w bytes.Buffer
+	if err := n.NormalizeLines(strings.NewReader("{\"b\":1,\"a\":2}\n[1]\n"), &w); err != nil {
+		t.Fatal(err)
+	} else if val := w.String(); val != "{\"a\":2,\"b\":1}\n[1]\n" {
+		t.Errorf("%q", val)
+	}
+	if s, err := n.NormalizeString(`1`); err != nil || s != "1\n" {
+		t.Errorf("%q, %v", s, err)
+	}
+}
+
+func TestWithComments(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithComments(true))
+
+	// top level
+	check(n, "// leading\n{\"a\": 1}", `{"a":1}`, nil)
+	check(n, "/* leading */ {\"a\": 1} // trailing", `{"a":1}`, nil)
+	check(n, "1 /* trailing */", `1`, nil)
+	check(n, "/**/1/***/", `1`, nil)
+
+	// inside objects
+	check(n, "{// first\n\"b\": 1, /* x */ \"a\" /* y */ : /* z */ 2 // last\n}", `{"a":2,"b":1}`, nil)
+	check(n, `{"a": 1/* after number */}`, `{"a":1}`, nil)
+
+	// inside arrays
+	check(n, "[1, // one\n 2 /* two */, /* three */ 3]", `[1,2,3]`, nil)
+	check(n, "[/* empty */]", `[]`, nil)
+
+	// comment-like content of strings is kept
+	check(n, `["// not a comment", "/* nor this */"]`, `["// not a comment","/* nor this */"]`, nil)
+
+	check(n, `[1, /* unterminated ]`, ``, JsonSyntaxError)
+	check(n, `/* unterminated *`, ``, JsonSyntaxError)
+	check(n, `[1 / 2]`, ``, JsonSyntaxError)
+	check(n, `1 /`, ``, JsonSyntaxError)
+
+	strict := New()
+	check(strict, "// comment\n1", ``, JsonSyntaxError)
+	check(strict, "[1 /* comment */]", ``, JsonSyntaxError)
+}
+
+func TestWithTrailingCommas(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithTrailingCommas(true))
+	check(n, `[1,2,]`, `[1,2]`, nil)
+	check(n, "[1, 2 ,\n]", `[1,2]`, nil)
+	check(n, `{"b":1,"a":2,}`, `{"a":2,"b":1}`, nil)
+	check(n, `{"a": [1, {"b": [],},], "c": {"d": 1 , } ,}`, `{"a":[1,{"b":[]}],"c":{"d":1}}`, nil)
+
+	check(n, `[,]`, ``, JsonSyntaxError)
+	check(n, `[1,,]`, ``, JsonSyntaxError)
+	check(n, `{,}`, ``, JsonSyntaxError)
+	check(n, `{"a":1,,}`, ``, JsonSyntaxError)
+
+	strict := New()
+	check(strict, `[1,2,]`, ``, JsonSyntaxError)
+	check(strict, `{"a":1,}`, ``, JsonSyntaxError)
+
+	// the tokenizer accepts them as well
+	tokens := func(n *Normalizer, src string) ([]TokenType, error) {
+		var types []TokenType
+		tok := n.NewTokenizer(strings.NewReader(src))
+		for {
+			token, err := tok.Next()
+			if err == io.EOF {
+				return types, nil
+			} else if err != nil {
+				return types, err
+			}
+			types = append(types, token.Type)
+		}
+	}
+	if types, err := tokens(n, `[1, {"a": [2,],},]`); err != nil {
+		t.Error(err)
+	} else if val := fmt.Sprint(types); val != "[ArrayStart Number ObjectStart Key ArrayStart Number ArrayEnd ObjectEnd ArrayEnd]" {
+		t.Error(val)
+	}
+	for _, src := range []string{`[,]`, `[1,,]`, `{,}`, `{"a":1,,}`} {
+		if _, err := tokens(n, src); !errors.Is(err, JsonSyntaxError) {
+			t.Errorf("%v != %v, src: %s", err, JsonSyntaxError, src)
+		}
+	}
+	if _, err := tokens(strict, `{"a":1,}`); !errors.Is(err, JsonSyntaxError) {
+		t.Errorf("%v != %v", err, JsonSyntaxError)
+	}
+}
+
+func TestWithCaseInsensitiveSort(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	src := `{"apple": 1, "Zebra": 2, "banana": 3, "Apple": 4, "ÉCLAIR": 5, "éclair": 6}`
+
+	check(New(), src, `{"Apple":4,"Zebra":2,"apple":1,"banana":3,"ÉCLAIR":5,"éclair":6}`)
+	check(New(WithCaseInsensitiveSort(true)), src, `{"Apple":4,"apple":1,"banana":3,"Zebra":2,"ÉCLAIR":5,"éclair":6}`)
+	check(New(WithCaseInsensitiveSort(true)), `{"b": {"B": 1, "a": 2}, "A": 3}`, `{"A":3,"b":{"a":2,"B":1}}`)
+}
+
+func TestWithKeyComparator(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	byLength := func(a, b string) bool {
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	}
+
+	src := `{"ccc": 1, "a": 2, "bb": 3, "aa": 4, "b": {"yy": 5, "x": 6}}`
+
+	check(New(WithKeyComparator(byLength)), src, `{"a":2,"b":{"x":6,"yy":5},"aa":4,"bb":3,"ccc":1}`)
+	check(New(WithKeyComparator(byLength), WithCaseInsensitiveSort(true)), `{"BB": 1, "a": 2}`, `{"a":2,"BB":1}`)
+	check(New(WithKeyComparator(nil)), src, `{"a":2,"aa":4,"b":{"x":6,"yy":5},"bb":3,"ccc":1}`)
+}
+
+func TestWithUTF16KeySort(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	// U+FF21 (fullwidth A) and U+E000 (private use) are encoded as a single
+	// code unit sorting after the surrogates of U+1F600 and U+10000
+	src := "{\"\uff21\": 1, \"\U0001f600\": 2, \"a\": 3, \"\ue000\": 4, \"\U00010000\": 5}"
+
+	check(New(), src, "{\"a\":3,\"\ue000\":4,\"\uff21\":1,\"\U00010000\":5,\"\U0001f600\":2}")
+	check(New(WithUTF16KeySort(true)), src, "{\"a\":3,\"\U00010000\":5,\"\U0001f600\":2,\"\ue000\":4,\"\uff21\":1}")
+	check(New(WithUTF16KeySort(true)), "{\"x\U0001f600\": 1, \"x\uffff\": 2, \"x\": 3}", "{\"x\":3,\"x\U0001f600\":1,\"x\uffff\":2}")
+}
+
+func TestWithNaturalKeySort(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithNaturalKeySort(true))
+	check(n, `{"item10": 1, "item2": 2, "item1": 3}`, `{"item1":3,"item2":2,"item10":1}`)
+	check(New(), `{"item10": 1, "item2": 2, "item1": 3}`, `{"item1":3,"item10":1,"item2":2}`)
+	check(n, `{"10": 1, "9": 2, "100": 3, "1": 4}`, `{"1":4,"9":2,"10":1,"100":3}`)
+	check(n, `{"a10b2": 1, "a10b10": 2, "a9b100": 3, "a": 4, "b": 5}`, `{"a":4,"a9b100":3,"a10b2":1,"a10b10":2,"b":5}`)
+	check(n, `{"v010": 1, "v10": 2, "v9": 3, "v0010": 4}`, `{"v9":3,"v0010":4,"v010":1,"v10":2}`)
+	check(n, `{"x1y": 1, "x1": 2, "x": 3, "x01": 4}`, `{"x":3,"x01":4,"x1":2,"x1y":1}`)
+	check(n, `{"18446744073709551617": 1, "18446744073709551616": 2, "9": 3}`, `{"9":3,"18446744073709551616":2,"18446744073709551617":1}`)
+	check(n, `[{"item10": 1, "item2": 2}, {"item2": 3, "item10": 4}]`, `[{"item2":2,"item10":1},{"item2":3,"item10":4}]`)
+
+	check(New(WithNaturalKeySort(true), WithCaseInsensitiveSort(true)), `{"Item10": 1, "item2": 2, "ITEM1": 3}`, `{"ITEM1":3,"item2":2,"Item10":1}`)
+
+	less := func(a, b string) bool { return b < a }
+	check(New(WithNaturalKeySort(true), WithKeyComparator(less)), `{"item10": 1, "item2": 2}`, `{"item2":2,"item10":1}`)
+}
+
+func TestWithSortDescending(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithSortDescending(true))
+	check(n, `{"a": 1, "c": 2, "b": 3}`, `{"c":2,"b":3,"a":1}`)
+	check(n, `{"b": {"x": 1, "y": [{"m": 1, "n": 2}]}, "a": 2}`, `{"b":{"y":[{"n":2,"m":1}],"x":1},"a":2}`)
+	check(n, `{"a": 1, "b": 2, "a": 3}`, `{"b":2,"a":1,"a":3}`)
+	check(n, `{"B": 1, "a": 2, "A": 3}`, `{"a":2,"B":1,"A":3}`)
+	check(New(WithSortDescending(true), WithDuplicateKeys(DuplicateKeysKeepLast)), `{"a": 1, "b": 2, "a": 3}`, `{"b":2,"a":3}`)
+
+	check(New(WithSortDescending(true), WithCaseInsensitiveSort(true)), `{"B": 1, "a": 2, "A": 3, "c": 4}`, `{"c":4,"B":1,"a":2,"A":3}`)
+	check(New(WithSortDescending(true), WithNaturalKeySort(true)), `{"item10": 1, "item2": 2, "item1": 3}`, `{"item10":1,"item2":2,"item1":3}`)
+	byLength := func(a, b string) bool { return len(a) < len(b) }
+	check(New(WithSortDescending(true), WithKeyComparator(byLength)), `{"aa": 1, "b": 2, "ccc": 3}`, `{"ccc":3,"aa":1,"b":2}`)
+
+	// no effect without sorting or with JCS
+	check(New(WithSortDescending(true), WithSortKeys(false)), `{"a": 1, "c": 2, "b": 3}`, `{"a":1,"c":2,"b":3}`)
+	check(New(WithSortDescending(true), WithJCS(true)), `{"a": 1, "c": 2, "b": 3}`, `{"a":1,"b":3,"c":2}`)
+}
+
+func TestWithOmitNulls(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithOmitNulls(true))
+	check(New(), `{"a":1,"b":null}`, `{"a":1,"b":null}`)
+	check(n, `{"a":1,"b":null}`, `{"a":1}`)
+	check(n, `{"b":null}`, `{}`)
+	check(n, `{"a":{"c":null,"d":[null, 1]},"b":null}`, `{"a":{"d":[null,1]}}`)
+	check(n, `{"a":"null"}`, `{"a":"null"}`)
+	check(n, `null`, `null`)
+	check(New(WithOmitNulls(true), WithIndent("", "  ")), `{"a":{"b":null}}`, "{\n  \"a\": {}\n}")
+
+	// the duplicate key policy applies before null members are dropped
+	check(New(WithOmitNulls(true), WithDuplicateKeys(DuplicateKeysKeepLast)), `{"a":1,"a":null,"b":2}`, `{"b":2}`)
+	check(New(WithOmitNulls(true), WithDuplicateKeys(DuplicateKeysKeepLast)), `{"a":null,"a":1}`, `{"a":1}`)
+	check(New(WithOmitNulls(true), WithDuplicateKeys(DuplicateKeysKeepFirst)), `{"a":null,"a":1,"b":2}`, `{"b":2}`)
+	check(New(WithOmitNulls(true), WithDuplicateKeys(DuplicateKeysKeepLast), WithSortKeys(false)),
+		`{"b":null,"a":1,"c":null,"b":2}`, `{"b":2,"a":1}`)
+	reject := New(WithOmitNulls(true), WithRejectDuplicateKeys(true))
+	if _, err := reject.Normalize([]byte(`{"a":1,"a":null}`)); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("%v != %v", err, ErrDuplicateKey)
+	}
+	if reject.Valid([]byte(`{"a":1,"a":null}`)) {
+		t.Error("duplicate null member reported as valid")
+	}
+}
+
+func TestWithOmitEmpty(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithOmitEmpty(true))
+	check(New(), `{"a":{},"b":[],"c":1}`, `{"a":{},"b":[],"c":1}`)
+	check(n, `{"a":{},"b":[],"c":1}`, `{"c":1}`)
+	check(n, `[1, [], {}, 2]`, `[1,2]`)
+	check(n, `{"a":{"b":{"c":[]}},"d":[[{}], null]}`, `{"d":[null]}`)
+	check(n, `{"a":{"b":[[], {"c":{}}]}}`, `{}`)
+	check(n, `[[[]]]`, `[]`)
+	check(n, `{"a":"", "b":0, "c":"{}"}`, `{"a":"","b":0,"c":"{}"}`)
+	check(New(WithOmitEmpty(true), WithOmitNulls(true)), `{"a":{"b":null},"c":[]}`, `{}`)
+	check(New(WithOmitEmpty(true), WithIndent("", "  ")), `{"a":[[]],"b":[1,{}]}`, "{\n  \"b\": [\n    1\n  ]\n}")
+	check(New(WithOmitEmpty(true), WithIndent("", "  ")), `[{}]`, "[]")
+
+	// the duplicate key policy applies before empty members are dropped
+	check(New(WithOmitEmpty(true), WithDuplicateKeys(DuplicateKeysKeepLast)), `{"a":1,"a":[],"b":2}`, `{"b":2}`)
+	check(New(WithOmitEmpty(true), WithDuplicateKeys(DuplicateKeysKeepFirst)), `{"a":{},"a":1}`, `{}`)
+	reject := New(WithOmitEmpty(true), WithRejectDuplicateKeys(true))
+	if _, err := reject.Normalize([]byte(`{"a":[],"a":{}}`)); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("%v != %v", err, ErrDuplicateKey)
+	}
+	if reject.Valid([]byte(`{"a":[],"a":{}}`)) {
+		t.Error("duplicate empty member reported as valid")
+	}
+}
+
+func TestWithMaxTokenLength(t *testing.T) {
+	check := func(n *Normalizer, src string, expectedError error) {
+		if _, err := n.Normalize([]byte(src)); !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %.40s", err, expectedError, src)
+		}
+	}
+
+	numbers := New(WithMaxNumberLength(5))
+	check(numbers, `12345`, nil)
+	check(numbers, `[-1e30]`, nil)
+	check(numbers, `123456`, ErrTokenTooLong)
+	check(numbers, `[-1.5e10]`, ErrTokenTooLong)
+	check(numbers, `"123456"`, nil)
+	check(numbers, strings.Repeat("1", 1<<20), ErrTokenTooLong)
+
+	strs := New(WithMaxStringLength(4))
+	check(strs, `"abcd"`, nil)
+	check(strs, `"\n\n\n\n"`, nil)
+	check(strs, `"ééé"`, ErrTokenTooLong)
+	check(strs, `"abcde"`, ErrTokenTooLong)
+	check(strs, `{"abcde": 1}`, ErrTokenTooLong)
+	check(strs, `123456789`, nil)
+	check(strs, `"`+strings.Repeat("x", 1<<20)+`"`, ErrTokenTooLong)
+
+	check(New(), `"`+strings.Repeat("x", 1<<20)+`"`, nil)
+}
+
+func TestWithMaxOutputSize(t *testing.T) {
+	check := func(n *Normalizer, src string, expectedError error) {
+		if _, err := n.Normalize([]byte(src)); !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %.40s", err, expectedError, src)
+		}
+	}
+
+	n := New(WithMaxOutputSize(10))
+	check(n, `"abcdefgh"`, nil)
+	check(n, `"abcdefghi"`, ErrOutputTooLarge)
+	check(n, `{ "b" : [ 1 ] }`, nil)
+	check(n, `{"b":[1],"a":22}`, ErrOutputTooLarge)
+	check(n, `[1,2,3,4,5]`, ErrOutputTooLarge)
+	check(n, `[[[[[[[[[[1]]]]]]]]]]`, ErrOutputTooLarge)
+	check(n, `1234567891`, nil)
+	check(n, `1e+100`, nil)
+
+	// the escaped output is three times the size of the input
+	ascii := New(WithASCIIOnly(true), WithMaxOutputSize(20))
+	check(ascii, `"éé"`, nil)
+	check(ascii, `"éééé"`, ErrOutputTooLarge)
+	check(ascii, `["`+strings.Repeat("é", 1<<16)+`"]`, ErrOutputTooLarge)
+
+	indent := New(WithIndent("", "    "), WithMaxOutputSize(20))
+	check(indent, `[1,2]`, nil)
+	check(indent, `[1,2,3]`, ErrOutputTooLarge)
+
+	// newlines between and after values count as well
+	check(New(WithMaxOutputSize(2), WithTrailingNewline(true)), `[]`, ErrOutputTooLarge)
+	check(New(WithMaxOutputSize(3), WithTrailingNewline(true)), `[]`, nil)
+	check(New(WithMaxOutputSize(3), WithAllowMultipleValues(true)), `1 2`, nil)
+	check(New(WithMaxOutputSize(2), WithAllowMultipleValues(true)), `1 2`, ErrOutputTooLarge)
+	check(New(WithMaxOutputSize(3), WithAllowMultipleValues(true), WithTrailingNewline(true)), `1 2`, ErrOutputTooLarge)
+
+	if !New(WithMaxOutputSize(1)).Valid([]byte(`[1, 2]`)) {
+		t.Error("Valid checks the output size")
+	}
+}
+
+func TestWithMaxTotalNodes(t *testing.T) {
+	check := func(n *Normalizer, src string, expectedError error) {
+		if _, err := n.Normalize([]byte(src)); !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %.40s", err, expectedError, src)
+		}
+	}
+
+	n := New(WithMaxTotalNodes(5))
+	check(n, `1`, nil)
+	check(n, `[1, 2, 3, 4]`, nil)
+	check(n, `[1, 2, 3, 4, 5]`, ErrMaxNodesExceeded)
+	check(n, `{"a": 1, "b": 2}`, nil)
+	check(n, `{"a": 1, "b": 2, "c": 3}`, ErrMaxNodesExceeded)
+	check(n, `[[[[1]]]]`, nil)
+	check(n, `[[[[[1]]]]]`, ErrMaxNodesExceeded)
+	check(n, `{"a": {"b": []}}`, nil)
+	check(n, `{"a": {"b": [null]}}`, ErrMaxNodesExceeded)
+
+	var siblings strings.Builder
+	siblings.WriteString("{")
+	for i := 0; i < 100000; i++ {
+		if i > 0 {
+			siblings.WriteString(",")
+		}
+		fmt.Fprintf(&siblings, `"k%d":%d`, i, i)
+	}
+	siblings.WriteString("}")
+	check(New(WithMaxTotalNodes(1000)), siblings.String(), ErrMaxNodesExceeded)
+	check(New(WithMaxTotalNodes(200001)), siblings.String(), nil)
+	if New(WithMaxTotalNodes(1000)).Valid([]byte(siblings.String())) {
+		t.Error("Valid ignores the node limit")
+	}
+
+	values, err := n.NormalizeStream(strings.NewReader(`[1, 2, 3] [4, 5, 6] [7, 8, 9, 10]`))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != 3 {
+		t.Errorf("%d != 3", len(values))
+	}
+}
+
+func TestWithASCIIOnly(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithASCIIOnly(true))
+	check(n, `"café"`, `"caf\u00e9"`)
+	check(n, `"Ærøskøbing"`, `"\u00c6r\u00f8sk\u00f8bing"`)
+	check(n, `"世界"`, `"\u4e16\u754c"`)
+	check(n, `"😀!"`, `"\ud83d\ude00!"`)
+	check(n, `{"ключ": "значение"}`, `{"\u043a\u043b\u044e\u0447":"\u0437\u043d\u0430\u0447\u0435\u043d\u0438\u0435"}`)
+	check(n, `"\u00e9\n"`, `"\u00e9\n"`)
+	check(n, `"plain"`, `"plain"`)
+
+	check(New(), `"\u00e9😀"`, `"é😀"`)
+
+	// RFC 8785 leaves non-ASCII characters as they are
+	check(New(WithJCS(true), WithASCIIOnly(true)), `{"é": "\u00e9😀"}`, `{"é":"é😀"}`)
+}
+
+func TestWithEscapeHTML(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithEscapeHTML(true))
+	check(n, `"<script>alert('x')</script>"`, `"\u003cscript\u003ealert('x')\u003c/script\u003e"`)
+	check(n, `{"a&b": "x > y"}`, `{"a\u0026b":"x \u003e y"}`)
+	check(n, `"\u003c"`, `"\u003c"`)
+
+	check(New(), `"<b>&</b>"`, `"<b>&</b>"`)
+	check(New(), `"\u003c"`, `"<"`)
+
+	// RFC 8785 leaves them as they are
+	check(New(WithJCS(true), WithEscapeHTML(true)), `{"<a>": "\u003c&"}`, `{"<a>":"<&"}`)
+}
+
+func TestWithSortArrays(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithSortArrays(true))
+	check(n, `[3, 1, 2]`, `[1,2,3]`)
+	check(n, `[1, 2, 3]`, `[1,2,3]`)
+	check(n, `["b", "a", "c", "a"]`, `["a","a","b","c"]`)
+	check(n, `[true, null, 1, "x", false]`, `["x",1,false,null,true]`)
+	check(n, `[{"b": 2, "a": 1}, {"a": 0}, [2, 1]]`, `[[1,2],{"a":0},{"a":1,"b":2}]`)
+	check(n, `{"x": [{"id": 2}, {"id": 1}]}`, `{"x":[{"id":1},{"id":2}]}`)
+	check(n, `[]`, `[]`)
+	check(New(WithSortArrays(true), WithTrailingCommas(true)), `[2, 1,]`, `[1,2]`)
+	check(New(WithSortArrays(true), WithOmitEmpty(true)), `[2, [], 1]`, `[1,2]`)
+	check(New(WithSortArrays(true), WithIndent("", " ")), `[[2], [1]]`, "[\n [\n  1\n ],\n [\n  2\n ]\n]")
+
+	check(New(), `[3, 1, 2]`, `[3,1,2]`)
+
+	if ok, err := n.Equal([]byte(`[3,1,2]`), []byte(`[1,2,3]`)); err != nil || !ok {
+		t.Errorf("%v, %v", ok, err)
+	}
+}
+
+func TestWithDedupArrays(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithDedupArrays(true))
+	check(n, `[1, 1, 2]`, `[1,2]`)
+	check(n, `[2, 1, 2, 1.0, 10e-1]`, `[2,1]`)
+	check(n, `["a", "a", "b"]`, `["a","b"]`)
+	check(n, `[{"a": 1, "b": 2}, {"b": 2, "a": 1}, {"a": 1}]`, `[{"a":1,"b":2},{"a":1}]`)
+	check(n, `[[1, 1], [1]]`, `[[1]]`)
+	check(n, `[]`, `[]`)
+
+	sorted := New(WithDedupArrays(true), WithSortArrays(true))
+	check(sorted, `[3, 1, 3, 2, 1]`, `[1,2,3]`)
+	check(sorted, `[{"b": 2, "a": 1}, {"a": 0}, {"a": 1, "b": 2}]`, `[{"a":0},{"a":1,"b":2}]`)
+
+	check(New(), `[1, 1, 2]`, `[1,1,2]`)
+}
+
+func TestWithStripBOM(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %q", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	const bom = "\xef\xbb\xbf"
+	check(New(), bom+`{"b": 2, "a": 1}`, `{"a":1,"b":2}`, nil)
+	check(New(), bom+" [1]\n", `[1]`, nil)
+	check(New(), bom+`"x"`, `"x"`, nil)
+	check(New(), `{"a": "`+bom+`"}`, `{"a":"`+bom+`"}`, nil)
+	check(New(), bom, ``, ErrEmptyInput)
+	check(New(), bom+bom+`{}`, ``, JsonSyntaxError)
+	check(New(), "\xef\xbb", ``, JsonSyntaxError)
+	check(New(), "\xef\xbf\xbd", ``, JsonSyntaxError)
+	check(New(), `{}`+bom, ``, ErrTrailingData)
+
+	check(New(WithStripBOM(false)), bom+`{}`, ``, JsonSyntaxError)
+	check(New(WithStripBOM(false)), `{}`, `{}`, nil)
+
+	var serr *SyntaxError
+	if _, err := Normalize([]byte(bom + "\n x")); !errors.As(err, &serr) {
+		t.Errorf("%v is not a SyntaxError", err)
+	} else if serr.Offset != 5 || serr.Line != 2 || serr.Column != 2 {
+		t.Errorf("%+v", serr)
+	}
+	if _, err := Normalize([]byte(bom + "x")); !errors.As(err, &serr) {
+		t.Errorf("%v is not a SyntaxError", err)
+	} else if serr.Offset != 3 || serr.Line != 1 || serr.Column != 1 {
+		t.Errorf("%+v", serr)
+	}
+
+	values, err := NormalizeStream(strings.NewReader(bom + `{"a":1} [2]`))
+	if err != nil || len(values) != 2 {
+		t.Errorf("%q, %v", values, err)
+	}
+}
+
+func TestWithSingleQuotes(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithSingleQuotes(true))
+	check(n, `{'a':'b'}`, `{"a":"b"}`, nil)
+	check(n, `{'b': 1, "a": 'x', 'c': "y"}`, `{"a":"x","b":1,"c":"y"}`, nil)
+	check(n, `['it\'s', "it\'s", 'say "hi"', "'"]`, `["it's","it's","say \"hi\"","'"]`, nil)
+	check(n, `'A\n\\'`, `"A\n\\"`, nil)
+	check(n, `{'a': ['x', {'y': ''}]}`, `{"a":["x",{"y":""}]}`, nil)
+
+	check(n, `'abc"`, ``, JsonSyntaxError)
+	check(n, `{'a":1}`, ``, JsonSyntaxError)
+	check(n, `'\x'`, ``, JsonSyntaxError)
+
+	strict := New()
+	check(strict, `{'a':1}`, ``, JsonSyntaxError)
+	check(strict, `['a']`, ``, JsonSyntaxError)
+	check(strict, `"it\'s"`, ``, JsonSyntaxError)
+
+	tok := n.NewTokenizer(strings.NewReader(`{'a': 'b'}`))
+	for _, expected := range []Token{{Type: ObjectStart}, {Type: Key, Value: []byte(`"a"`)}, {Type: String, Value: []byte(`"b"`)}, {Type: ObjectEnd}} {
+		if token, err := tok.Next(); err != nil {
+			t.Fatal(err)
+		} else if token.Type != expected.Type || string(token.Value) != string(expected.Value) {
+			t.Errorf("%v %s != %v %s", token.Type, token.Value, expected.Type, expected.Value)
+		}
+	}
+}
+
+func TestWithUnquotedKeys(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithUnquotedKeys(true))
+	check(n, `{a:1,b2:2}`, `{"a":1,"b2":2}`, nil)
+	check(n, `{ b : 1, "a": 2, _c$: {$: [true]} }`, `{"_c$":{"$":[true]},"a":2,"b":1}`, nil)
+	check(n, `{null: 1, true: 2}`, `{"null":1,"true":2}`, nil)
+
+	check(n, `{1a:1}`, ``, JsonSyntaxError)
+	check(n, `{a-b:1}`, ``, JsonSyntaxError)
+	check(n, `{a b:1}`, ``, JsonSyntaxError)
+	check(n, `{é:1}`, ``, JsonSyntaxError)
+	check(n, `{a`, ``, JsonSyntaxError)
+	check(n, `[a]`, ``, JsonSyntaxError)
+
+	strict := New()
+	check(strict, `{a:1}`, ``, JsonSyntaxError)
+
+	if _, err := New(WithUnquotedKeys(true), WithMaxStringLength(3)).Normalize([]byte(`{abcd:1}`)); !errors.Is(err, ErrTokenTooLong) {
+		t.Errorf("%v != %v", err, ErrTokenTooLong)
+	}
+
+	tok := n.NewTokenizer(strings.NewReader(`{a: 1}`))
+	for _, expected := range []Token{{Type: ObjectStart}, {Type: Key, Value: []byte(`"a"`)}, {Type: Number, Value: []byte(`1`)}, {Type: ObjectEnd}} {
+		if token, err := tok.Next(); err != nil {
+			t.Fatal(err)
+		} else if token.Type != expected.Type || string(token.Value) != string(expected.Value) {
+			t.Errorf("%v %s != %v %s", token.Type, token.Value, expected.Type, expected.Value)
+		}
+	}
+}
+
+func TestWithUnicodeWhitespace(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %q", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	strict := New()
+	check(strict, " \t\n\r[1, 2]\r\n ", `[1,2]`, nil)
+	for _, space := range []string{"\v", "\f", "\u00A0", "\uFEFF", "\u2028", "\u2029", "\u3000", "\u0085"} {
+		check(strict, "["+space+"1]", ``, JsonSyntaxError)
+		check(strict, "[1,"+space+"2]", ``, JsonSyntaxError)
+		check(strict, "{"+space+"\"a\":1}", ``, JsonSyntaxError)
+		check(strict, "1"+space, ``, JsonSyntaxError)
+	}
+
+	n := New(WithUnicodeWhitespace(true))
+	check(n, "\v[\f1,\u00A02\u2028]\u3000", `[1,2]`, nil)
+	check(n, "{\uFEFF\"b\"\u2029:1,\u205F\"a\"\u1680:2}", `{"a":2,"b":1}`, nil)
+	check(n, "[\"\u00A0\"]", "[\"\u00A0\"]", nil)
+	check(n, "[1\u0085]", ``, JsonSyntaxError)
+	check(n, "[1\u00E9]", ``, JsonSyntaxError)
+	check(n, "[1\xC2]", ``, JsonSyntaxError)
+
+	var syntaxErr *SyntaxError
+	if _, err := n.Normalize([]byte("[1,\u00A0x]")); !errors.As(err, &syntaxErr) {
+		t.Errorf("unexpected error: %v", err)
+	} else if syntaxErr.Offset != 5 {
+		t.Errorf("%d != 5", syntaxErr.Offset)
+	}
+}
+
+func TestWithAllowNonFiniteNumbers(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithAllowNonFiniteNumbers(true))
+	check(n, `NaN`, `NaN`, nil)
+	check(n, `Infinity`, `Infinity`, nil)
+	check(n, `-Infinity`, `-Infinity`, nil)
+	check(n, `{"b": -Infinity, "a": [NaN, Infinity, -1]}`, `{"a":[NaN,Infinity,-1],"b":-Infinity}`, nil)
+
+	check(n, `Nan`, ``, JsonSyntaxError)
+	check(n, `Infinit`, ``, JsonSyntaxError)
+	check(n, `-Inf`, ``, JsonSyntaxError)
+	check(n, `+Infinity`, ``, JsonSyntaxError)
+	check(n, `-NaN`, ``, JsonSyntaxError)
+	check(n, `1Infinity`, ``, JsonSyntaxError)
+
+	asNull := New(WithAllowNonFiniteNumbers(true), WithNonFiniteAsNull(true))
+	check(asNull, `[NaN, Infinity, -Infinity, 1]`, `[null,null,null,1]`, nil)
+	check(New(WithAllowNonFiniteNumbers(true), WithNonFiniteAsNull(true), WithOmitNulls(true)), `{"a": NaN, "b": 1}`, `{"b":1}`, nil)
+
+	strict := New()
+	check(strict, `NaN`, ``, JsonSyntaxError)
+	check(strict, `Infinity`, ``, JsonSyntaxError)
+	check(strict, `-Infinity`, ``, JsonSyntaxError)
+	check(strict, `[1, NaN]`, ``, JsonSyntaxError)
+	check(New(WithNonFiniteAsNull(true)), `NaN`, ``, JsonSyntaxError)
+
+	tok := asNull.NewTokenizer(strings.NewReader(`[-Infinity]`))
+	for _, expected := range []Token{{Type: ArrayStart}, {Type: Null, Value: []byte(`null`)}, {Type: ArrayEnd}} {
+		if token, err := tok.Next(); err != nil {
+			t.Fatal(err)
+		} else if token.Type != expected.Type || string(token.Value) != string(expected.Value) {
+			t.Errorf("%v %s != %v %s", token.Type, token.Value, expected.Type, expected.Value)
+		}
+	}
+}
+
+func TestWithKeyNFC(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	n := New(WithKeyNFC(true))
+	check(n, `{"cafe\u0301x": 1, "caf\u00e9": 2, "a\u030a": 3}`, "{\"caf\u00e9\":2,\"caf\u00e9x\":1,\"\u00e5\":3}", nil)
+	check(n, `{"cafe\u0301": 1, "caf\u00e9": 2}`, "{\"caf\u00e9\":1,\"caf\u00e9\":2}", nil)
+	check(n, `["cafe\u0301"]`, "[\"cafe\u0301\"]", nil)
+	check(New(WithKeyNFC(true), WithSortKeys(false)), `{"b": 1, "cafe\u0301": 2}`, "{\"b\":1,\"caf\u00e9\":2}", nil)
+
+	// keys which become equal are duplicates
+	check(New(WithKeyNFC(true), WithDuplicateKeys(DuplicateKeysError)), `{"cafe\u0301": 1, "caf\u00e9": 2}`, ``, ErrDuplicateKey)
+	check(New(WithKeyNFC(true), WithDuplicateKeys(DuplicateKeysKeepLast)), `{"cafe\u0301": 1, "caf\u00e9": 2}`, "{\"caf\u00e9\":2}", nil)
+
+	composed, decomposed := []byte(`{"caf\u00e9": 1}`), []byte(`{"cafe\u0301": 1}`)
+	if ok, err := n.Equal(composed, decomposed); err != nil || !ok {
+		t.Errorf("%v, %v", ok, err)
+	}
+
+	check(New(), `{"cafe\u0301": 1, "caf\u00e9": 2}`, "{\"cafe\u0301\":1,\"caf\u00e9\":2}", nil)
+}
+
+func TestWithKeyTransformer(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	// composes the decomposed characters used below like NFC does
+	nfc := func(key string) (string, error) {
+		return strings.NewReplacer("e\u0301", "\u00e9", "a\u030a", "\u00e5").Replace(key), nil
+	}
+
+	n := New(WithKeyTransformer(nfc))
+	check(n, `{"cafe\u0301": 1, "caf\u00e9x": 2, "a\u030a": 3}`, "{\"caf\u00e9\":1,\"caf\u00e9x\":2,\"\u00e5\":3}", nil)
+	check(n, `{"cafe\u0301": 1, "caf\u00e9": 2}`, "{\"caf\u00e9\":1,\"caf\u00e9\":2}", nil)
+	check(n, `["cafe\u0301"]`, "[\"cafe\u0301\"]", nil)
+
+	check(New(WithKeyTransformer(nfc), WithDuplicateKeys(DuplicateKeysError)), `{"cafe\u0301": 1, "caf\u00e9": 2}`, ``, ErrDuplicateKey)
+	check(New(WithKeyTransformer(nfc), WithDuplicateKeys(DuplicateKeysKeepLast)), `{"cafe\u0301": 1, "caf\u00e9": 2}`, "{\"caf\u00e9\":2}", nil)
+
+	errTransform := errors.New("transform failed")
+	fail := New(WithKeyTransformer(func(key string) (string, error) {
+		return "", errTransform
+	}))
+	check(fail, `{"a": 1}`, ``, errTransform)
+	check(fail, `[1]`, `[1]`, nil)
+
+	check(New(), `{"cafe\u0301": 1, "caf\u00e9": 2}`, "{\"cafe\u0301\":1,\"caf\u00e9\":2}", nil)
+}
+
+func TestWithValueNFC(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	n := New(WithValueNFC(true))
+	check(n, `"cafe\u0301"`, "\"caf\u00e9\"")
+	check(n, `{"e\u0301": ["a\u030a", "\u00e5", 1]}`, "{\"e\u0301\":[\"\u00e5\",\"\u00e5\",1]}")
+	check(n, `"plain"`, `"plain"`)
+
+	composed, decomposed := []byte(`{"name": "caf\u00e9"}`), []byte(`{"name": "cafe\u0301"}`)
+	if a, err := n.Hash(composed); err != nil {
+		t.Error(err)
+	} else if b, err := n.Hash(decomposed); err != nil {
+		t.Error(err)
+	} else if a != b {
+		t.Errorf("hashes differ: %x, %x", a, b)
+	}
+	if a, err := Hash(composed); err != nil {
+		t.Error(err)
+	} else if b, err := Hash(decomposed); err != nil {
+		t.Error(err)
+	} else if a == b {
+		t.Errorf("hashes equal without NFC: %x", a)
+	}
+
+	check(New(), `"cafe\u0301"`, "\"cafe\u0301\"")
+}
+
+func TestWithStringTransformer(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	// composes the decomposed characters used below like NFC does
+	nfc := func(s string) (string, error) {
+		return strings.NewReplacer("e\u0301", "\u00e9", "a\u030a", "\u00e5").Replace(s), nil
+	}
+
+	n := New(WithStringTransformer(nfc))
+	check(n, `"cafe\u0301"`, "\"caf\u00e9\"", nil)
+	check(n, `{"e\u0301": ["a\u030a", 1]}`, "{\"e\u0301\":[\"\u00e5\",1]}", nil)
+
+	composed, decomposed := []byte(`{"name": "caf\u00e9"}`), []byte(`{"name": "cafe\u0301"}`)
+	if a, err := n.Hash(composed); err != nil {
+		t.Error(err)
+	} else if b, err := n.Hash(decomposed); err != nil {
+		t.Error(err)
+	} else if a != b {
+		t.Errorf("hashes differ: %x, %x", a, b)
+	}
+	if ok, err := Equal(composed, decomposed); err != nil || ok {
+		t.Errorf("%v, %v", ok, err)
+	}
+
+	trim := New(WithStringTransformer(func(s string) (string, error) {
+		return strings.TrimSpace(s), nil
+	}))
+	check(trim, `" hello "`, `"hello"`, nil)
+	check(trim, `{" key ": ["  a b  ", "\t\nc\r\n", " ", 1]}`, `{" key ":["a b","c","",1]}`, nil)
+
+	errTransform := errors.New("transform failed")
+	fail := New(WithStringTransformer(func(s string) (string, error) {
+		return "", errTransform
+	}))
+	check(fail, `{"a": "b"}`, ``, errTransform)
+	check(fail, `{"a": 1}`, `{"a":1}`, nil)
+	if fail.Valid([]byte(`["x"]`)) {
+		t.Error("valid despite the transformer failing")
+	}
+}
+
+func TestWithTrimStringValues(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	n := New(WithTrimStringValues(true))
+	check(n, `" hello "`, `"hello"`)
+	check(n, `"hello  world"`, `"hello  world"`)
+	check(n, `["\t a \n", "\u00a0b\u2003", "   ", ""]`, `["a","b","",""]`)
+	check(n, `{" b ": " x ", " a": {"c ": [" y"]}}`, `{" a":{"c ":["y"]}," b ":"x"}`)
+	check(New(), `" hello "`, `" hello "`)
+
+	// trimmed strings are equal and may become duplicates
+	check(New(WithTrimStringValues(true), WithDedupArrays(true)), `["a", " a", "a "]`, `["a"]`)
+	check(New(WithTrimStringValues(true), WithStringTransformer(func(s string) (string, error) {
+		return "[" + s + "]", nil
+	})), `" a "`, `"[a]"`)
+}
+
+func TestWithRecurseEmbeddedJSON(t *testing.T) {
+	check := func(n *Normalizer, src, expected string) {
+		data, err := n.Normalize([]byte(src))
+		if err != nil {
+			t.Errorf("%v, src: %s", err, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%q != %q", val, expected)
+		}
+	}
+
+	n := New(WithRecurseEmbeddedJSON(true))
+	check(n, `{"data": "{\"b\":2,\"a\":1}"}`, `{"data":"{\"a\":1,\"b\":2}"}`)
+	check(n, `[" [ 1.0, {\"y\": null, \"x\": \"{\\\"d\\\": 1, \\\"c\\\": 2}\"} ] "]`,
+		`["[1,{\"x\":\"{\\\"c\\\":2,\\\"d\\\":1}\",\"y\":null}]"]`)
+
+	// plain strings, other values and malformed JSON are left alone
+	check(n, `"hello"`, `"hello"`)
+	check(n, `["1.0", "null", "", " ", "{x", "[1] 2", "{\"a\": 1,}"]`,
+		`["1.0","null",""," ","{x","[1] 2","{\"a\": 1,}"]`)
+
+	// keys are not normalized
+	check(n, `{"{\"b\":2,\"a\":1}": 1}`, `{"{\"b\":2,\"a\":1}":1}`)
+
+	// the embedded JSON is compact and uses the same settings
+	check(New(WithRecurseEmbeddedJSON(true), WithIndent("", " "), WithTrailingNewline(true), WithTrailingCommas(true)),
+		`{"a": "{\"c\": [1, ], \"b\": 2}"}`, "{\n \"a\": \"{\\\"b\\\":2,\\\"c\\\":[1]}\"\n}\n")
+
+	check(New(), `{"data": "{\"b\":2,\"a\":1}"}`, `{"data":"{\"b\":2,\"a\":1}"}`)
+}
+
+func TestWithNumberFormatter(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	money := New(WithNumberFormatter(func(raw []byte) ([]byte, error) {
+		f, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendFloat(nil, f, 'f', 2, 64), nil
+	}))
+	check(money, `1`, `1.00`, nil)
+	check(money, `{"price": 9.999, "tax": 1.5e-1, "total": -0.004}`, `{"price":10.00,"tax":0.15,"total":-0.00}`, nil)
+	check(money, `[12.345, "12.345", 1e2]`, `[12.35,"12.345",100.00]`, nil)
+	check(money, `[1.]`, ``, JsonSyntaxError)
+
+	var raw []string
+	n := New(WithNumberFormatter(func(b []byte) ([]byte, error) {
+		raw = append(raw, string(b))
+		return b, nil
+	}))
+	check(n, `[1.50, -0, 1E+2, 100]`, `[1.50,-0,1E+2,100]`, nil)
+	if val := strings.Join(raw, " "); val != `1.50 -0 1E+2 100` {
+		t.Errorf("unexpected literals: %s", val)
+	}
+
+	errFormat := errors.New("format failed")
+	fail := New(WithNumberFormatter(func([]byte) ([]byte, error) {
+		return nil, errFormat
+	}))
+	check(fail, `{"a": 1}`, ``, errFormat)
+	check(fail, `{"a": "1"}`, `{"a":"1"}`, nil)
+
+	jcs := New(WithJCS(true), WithNumberFormatter(func([]byte) ([]byte, error) {
+		return nil, errFormat
+	}))
+	check(jcs, `[1.50]`, `[1.5]`, nil)
+}
+
+func TestWithRejectDuplicateKeys(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedKey string) {
+		data, err := n.Normalize([]byte(src))
+		var derr *DuplicateKeyError
+		if expectedKey == "" {
+			if err != nil {
+				t.Errorf("%v, src: %s", err, src)
+			} else if val := string(data); val != expected {
+				t.Errorf("%v != %v", val, expected)
+			}
+		} else if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &derr) {
+			t.Errorf("%v is not a DuplicateKeyError, src: %s", err, src)
+		} else if derr.Key != expectedKey {
+			t.Errorf("%q != %q, src: %s", derr.Key, expectedKey, src)
+		}
+	}
+
+	n := New(WithRejectDuplicateKeys(true))
+	check(n, `{"a": 1, "b": 2}`, `{"a":1,"b":2}`, "")
+	check(n, `{"a": 1, "b": 2, "a": 3}`, ``, "a")
+	check(n, `{"x": {"y\"z": 1, "y\u0022z": 2}}`, ``, `y"z`)
+	check(n, `[{"a": 1}, {"a": 2}]`, `[{"a":1},{"a":2}]`, "")
+
+	check(New(WithRejectDuplicateKeys(true), WithRejectDuplicateKeys(false)), `{"a": 1, "a": 2}`, `{"a":1,"a":2}`, "")
+	check(New(), `{"a": 1, "a": 2}`, `{"a":1,"a":2}`, "")
+
+	_, err := n.Normalize([]byte(`{"key": 1, "key": 2}`))
+	if val := err.Error(); val != `Duplicate key "key"` {
+		t.Errorf("unexpected message: %s", val)
+	}
+}
+
+func TestWithCaseInsensitiveLiterals(t *testing.T) {
+	check := func(n *Normalizer, src, expected string, expectedError error) {
+		data, err := n.Normalize([]byte(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	n := New(WithCaseInsensitiveLiterals(true))
+	check(n, `True`, `true`, nil)
+	check(n, `FALSE`, `false`, nil)
+	check(n, `NULL`, `null`, nil)
+	check(n, `nULl`, `null`, nil)
+	check(n, `{"b": [TRUE, False, tRUE], "a": Null}`, `{"a":null,"b":[true,false,true]}`, nil)
+	check(n, `Tru`, ``, ErrUnexpectedEnd)
+	check(n, `Nul1`, ``, JsonSyntaxError)
+	check(n, `NaN`, ``, JsonSyntaxError)
+	check(n, `{"a": Yes}`, ``, JsonSyntaxError)
+
+	// N starts NaN only when it is allowed
+	nonFinite := New(WithCaseInsensitiveLiterals(true), WithAllowNonFiniteNumbers(true))
+	check(nonFinite, `[NaN, NULL, Null, nan]`, ``, JsonSyntaxError)
+	check(nonFinite, `[NaN, NULL, Null, Infinity]`, `[NaN,null,null,Infinity]`, nil)
+	check(nonFinite, `NAN`, ``, JsonSyntaxError)
+
+	topLevel := New(WithCaseInsensitiveLiterals(true), WithAllowNonFiniteNumbers(true), WithAllowedTopLevel(KindNull))
+	check(topLevel, `NULL`, `null`, nil)
+	check(topLevel, `NaN`, ``, ErrTopLevelNotAllowed)
+	check(topLevel, `FALSE`, ``, ErrTopLevelNotAllowed)
+	if kind, err := nonFinite.KindOf([]byte(` Null`)); kind != KindNull || err != nil {
+		t.Errorf("%v, %v", kind, err)
+	}
+	if kind, err := nonFinite.KindOf([]byte(`NaN`)); kind != KindNumber || err != nil {
+		t.Errorf("%v, %v", kind, err)
+	}
+
+	strict := New()
+	check(strict, `True`, ``, JsonSyntaxError)
+	check(strict, `FALSE`, ``, JsonSyntaxError)
+	check(strict, `NULL`, ``, JsonSyntaxError)
+	check(strict, `[nulL]`, ``, JsonSyntaxError)
+	if _, err := strict.KindOf([]byte(`True`)); !errors.Is(err, JsonSyntaxError) {
+		t.Errorf("%v != %v", err, JsonSyntaxError)
+	}
+}
@@ -0,0 +1,135 @@
+package normalizer
+
+import (
+	"strconv"
+)
+
+// Get normalizes src and returns the normalized value found at path. Each
+// element of path is either an object key or a decimal array index, an empty
+// path selects the whole document.
+func Get(src []byte, path ...string) ([]byte, error) {
+	return defaultNormalizer.Get(src, path...)
+}
+
+// Get normalizes src and returns the normalized value found at path.
+func (n *Normalizer) Get(src []byte, path ...string) ([]byte, error) {
+	if len(path) == 0 {
+		return n.Normalize(src)
+	}
+
+	// the path is looked up in the compact output, the value found is laid
+	// out afterwards
+	c := n.compact()
+	data, err := c.Normalize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := c.newOutputParser(data)
+	for _, elem := range path {
+		if err := p.seek(elem); err != nil {
+			return nil, err
+		}
+	}
+	val, err := p.sliceValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return n.relayout(val)
+}
+
+// seek enters the container which starts at the next byte and moves to the
+// value selected by elem, so that it is read next.
+func (p *parser) seek(elem string) error {
+	if err := p.skipFillers(); err != nil {
+		return err
+	}
+	c, err := p.readByte()
+	if err != nil {
+		return p.truncated(err)
+	}
+
+	switch c {
+	case '{':
+		return p.seekMember(elem)
+	case '[':
+		if idx, err := strconv.ParseUint(elem, 10, 0); err == nil {
+			return p.seekElement(idx)
+		}
+	}
+	return ErrPathNotFound
+}
+
+func (p *parser) seekMember(key string) error {
+	if closed, err := p.parseClosing('}'); err != nil {
+		return err
+	} else if closed {
+		return ErrPathNotFound
+	}
+
+	for {
+		if name, err := p.parseName(); err != nil {
+			return p.truncated(err)
+		} else if name == key {
+			return nil
+		}
+		if err := p.skipValue(); err != nil {
+			return err
+		}
+		if err := p.seekNext('}'); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) seekElement(idx uint64) error {
+	if closed, err := p.parseClosing(']'); err != nil {
+		return err
+	} else if closed {
+		return ErrPathNotFound
+	}
+
+	for ; idx > 0; idx-- {
+		if err := p.skipValue(); err != nil {
+			return err
+		}
+		if err := p.seekNext(']'); err != nil {
+			return err
+		}
+	}
+	return p.skipFillers()
+}
+
+// seekNext consumes the comma before the next member or element, the end of
+// the container means that the path is not found.
+func (p *parser) seekNext(end byte) error {
+	if err := p.skipFillers(); err != nil {
+		return err
+	}
+	if c, err := p.readByte(); err != nil {
+		return p.truncated(err)
+	} else if c == end {
+		return ErrPathNotFound
+	} else if c != ',' {
+		return p.syntaxError()
+	}
+	return p.skipFillers()
+}
+
+// sliceValue returns the next value, which is part of data, the input of p.
+func (p *parser) sliceValue(data []byte) ([]byte, error) {
+	start := p.pos.offset
+	if err := p.skipValue(); err != nil {
+		return nil, err
+	}
+	return data[start:p.pos.offset], nil
+}
+
+// skipValue parses the next value without building its output.
+func (p *parser) skipValue() error {
+	p.discard = true
+	defer func() {
+		p.discard = false
+	}()
+	_, err := p.parseValue(nil)
+	return err
+}
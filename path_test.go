@@ -0,0 +1,84 @@
+package normalizer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	const doc = `{
+		"b": [1, {"y": 2.50, "x": [true, null]}, "s"],
+		"a": {"b": [{"c": "d"}, 10e1], "e": {}},
+		"": "empty"
+	}`
+
+	check := func(n *Normalizer, src string, path []string, expected string, expectedError error) {
+		data, err := n.Get([]byte(src), path...)
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, path: %q", err, expectedError, path)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v, path: %q", val, expected, path)
+		}
+	}
+
+	n := New()
+	check(n, doc, nil, `{"":"empty","a":{"b":[{"c":"d"},100],"e":{}},"b":[1,{"x":[true,null],"y":2.5},"s"]}`, nil)
+	check(n, doc, []string{"a"}, `{"b":[{"c":"d"},100],"e":{}}`, nil)
+	check(n, doc, []string{"a", "b", "0"}, `{"c":"d"}`, nil)
+	check(n, doc, []string{"a", "b", "0", "c"}, `"d"`, nil)
+	check(n, doc, []string{"a", "b", "1"}, `100`, nil)
+	check(n, doc, []string{"a", "e"}, `{}`, nil)
+	check(n, doc, []string{"b", "1", "x", "1"}, `null`, nil)
+	check(n, doc, []string{"b", "2"}, `"s"`, nil)
+	check(n, doc, []string{""}, `"empty"`, nil)
+
+	check(n, doc, []string{"c"}, ``, ErrPathNotFound)
+	check(n, doc, []string{"a", "b", "2"}, ``, ErrPathNotFound)
+	check(n, doc, []string{"a", "b", "-1"}, ``, ErrPathNotFound)
+	check(n, doc, []string{"a", "b", "x"}, ``, ErrPathNotFound)
+	check(n, doc, []string{"a", "e", "x"}, ``, ErrPathNotFound)
+	check(n, doc, []string{"a", "b", "1", "x"}, ``, ErrPathNotFound)
+	check(n, doc, []string{"b", "0", "0"}, ``, ErrPathNotFound)
+	check(n, `[]`, []string{"0"}, ``, ErrPathNotFound)
+	check(n, `{"a":x}`, []string{"a"}, ``, JsonSyntaxError)
+
+	pretty := New(WithIndent("", "  "))
+	check(pretty, doc, []string{"a", "b"}, "[\n  {\n    \"c\": \"d\"\n  },\n  100\n]", nil)
+
+	// the layout is not valid input, the path is looked up in the compact form
+	prefixed := New(WithIndent("// ", "  "))
+	check(prefixed, `{"a":[1]}`, []string{"a"}, "[\n//   1\n// ]", nil)
+	check(prefixed, `{"a":[1]}`, []string{"a", "0"}, `1`, nil)
+	check(prefixed, `{"a":[1]}`, nil, "{\n//   \"a\": [\n//     1\n//   ]\n// }", nil)
+	check(New(WithSeparators(" ;", "=")), `{"a":{"c":1,"b":[2]}}`, []string{"a"}, `{"b"=[2] ;"c"=1}`, nil)
+	check(New(WithTrailingNewline(true)), `{"a":[1]}`, []string{"a"}, `[1]`, nil)
+
+	keepLast := New(WithDuplicateKeys(DuplicateKeysKeepLast))
+	check(keepLast, `{"a":1,"a":2}`, []string{"a"}, `2`, nil)
+
+	// the path is looked up in the output, the hooks are not applied again
+	keys := New(WithKeyTransformer(func(key string) (string, error) {
+		return strings.ToUpper(key) + "_", nil
+	}))
+	check(keys, `{"a":{"b":1}}`, nil, `{"A_":{"B_":1}}`, nil)
+	check(keys, `{"a":{"b":1}}`, []string{"A_", "B_"}, `1`, nil)
+	check(keys, `{"a":{"b":1}}`, []string{"A__"}, ``, ErrPathNotFound)
+
+	strs := New(WithStringTransformer(func(decoded string) (string, error) {
+		return "x" + decoded, nil
+	}))
+	check(strs, `{"a":"b"}`, nil, `{"a":"xb"}`, nil)
+	check(strs, `{"a":"b"}`, []string{"a"}, `"xb"`, nil)
+	check(strs, `{"a":["b"]}`, []string{"a"}, `["xb"]`, nil)
+
+	nums := New(WithNumberFormatter(func(raw []byte) ([]byte, error) {
+		return append(raw[:len(raw):len(raw)], '0'), nil
+	}))
+	check(nums, `{"a":1.5}`, nil, `{"a":1.50}`, nil)
+	check(nums, `{"a":1.5}`, []string{"a"}, `1.50`, nil)
+
+	if val, err := Get([]byte(doc), "a", "b", "0", "c"); err != nil || string(val) != `"d"` {
+		t.Errorf("%s, %v", val, err)
+	}
+}
@@ -0,0 +1,74 @@
+package normalizer
+
+import (
+	"sync"
+)
+
+// maxPooledSize keeps exceptionally large scratch buffers from being retained
+// by the pools.
+const maxPooledSize = 64 << 10
+
+// bytesPool holds scratch buffers for decoded strings and the output of
+// containers. The pools store pointers so that putting a buffer back does not
+// allocate.
+var bytesPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 128)
+		return &b
+	},
+}
+
+// itemsPool holds the member lists used while parsing objects.
+var itemsPool = sync.Pool{
+	New: func() interface{} {
+		items := make([]_ObjItem, 0, 16)
+		return &items
+	},
+}
+
+func getBytes() *[]byte {
+	return bytesPool.Get().(*[]byte)
+}
+
+// putBytes returns b, which was grown from the buffer stored in bp, to the
+// pool. b must not be used afterwards.
+func putBytes(bp *[]byte, b []byte) {
+	if cap(b) > maxPooledSize {
+		return
+	}
+	*bp = b[:0]
+	bytesPool.Put(bp)
+}
+
+// growBytes makes room for at least n more bytes in b. Unlike append it keeps
+// doubling the capacity of large buffers, so that a long string is copied a
+// few times only.
+func growBytes(b []byte, n int) []byte {
+	if cap(b)-len(b) >= n {
+		return b
+	}
+	size := 2 * cap(b)
+	if size < len(b)+n {
+		size = len(b) + n
+	}
+	grown := make([]byte, len(b), size)
+	copy(grown, b)
+	return grown
+}
+
+func getItems() *[]_ObjItem {
+	return itemsPool.Get().(*[]_ObjItem)
+}
+
+// putItems returns items, which was grown from the list stored in ip, to the
+// pool. The members are cleared so that their values can be collected.
+func putItems(ip *[]_ObjItem, items []_ObjItem) {
+	if cap(items) > maxPooledSize {
+		return
+	}
+	for i := range items {
+		items[i] = _ObjItem{}
+	}
+	*ip = items[:0]
+	itemsPool.Put(ip)
+}
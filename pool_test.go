@@ -0,0 +1,35 @@
+package normalizer
+
+import (
+	"testing"
+)
+
+func TestPutItemsClearsMembers(t *testing.T) {
+	ip := getItems()
+	items := append((*ip)[:0], _ObjItem{name: "a", start: 1, end: 6})
+	putItems(ip, items)
+
+	if len(*ip) != 0 {
+		t.Errorf("pooled list is not empty: %d", len(*ip))
+	}
+	if it := items[:1][0]; it.name != "" || it.end != 0 {
+		t.Errorf("pooled member is not cleared: %v", it)
+	}
+}
+
+func TestPooledBuffersAreNotShared(t *testing.T) {
+	first, err := Normalize([]byte(`{"b": "abc", "a": ["x", "y"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := string(first)
+
+	for i := 0; i < 100; i++ {
+		if _, err := Normalize([]byte(`{"d": "zzzzzzz", "c": ["wwww", "vvvv"]}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if val := string(first); val != expected {
+		t.Errorf("%v != %v", val, expected)
+	}
+}
@@ -0,0 +1,33 @@
+package normalizer
+
+import (
+	"io"
+)
+
+// Result is a normalized document. It implements io.WriterTo and
+// fmt.Stringer, so that it can be handed to streaming code as is.
+type Result []byte
+
+// WriteTo writes the document to w.
+func (r Result) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(r)
+	return int64(n), err
+}
+
+func (r Result) String() string {
+	return string(r)
+}
+
+// NormalizeResult normalizes src using the default settings.
+func NormalizeResult(src []byte) (Result, error) {
+	return defaultNormalizer.NormalizeResult(src)
+}
+
+// NormalizeResult is like Normalize but returns the document as a Result.
+func (n *Normalizer) NormalizeResult(src []byte) (Result, error) {
+	data, err := n.Normalize(src)
+	if err != nil {
+		return nil, err
+	}
+	return Result(data), nil
+}
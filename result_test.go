@@ -0,0 +1,51 @@
+package normalizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestNormalizeResult(t *testing.T) {
+	res, err := NormalizeResult([]byte(`{"b": [1, 2.0], "a": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `{"a":"x","b":[1,2]}`
+	var buf bytes.Buffer
+	if n, err := res.WriteTo(&buf); err != nil {
+		t.Error(err)
+	} else if n != int64(len(expected)) || buf.String() != expected {
+		t.Errorf("%v, %d != %v, %d", buf.String(), n, expected, len(expected))
+	}
+
+	var _ io.WriterTo = res
+	var _ fmt.Stringer = res
+	if val := res.String(); val != expected {
+		t.Errorf("%v != %v", val, expected)
+	}
+	if val := fmt.Sprint(res); val != expected {
+		t.Errorf("%v != %v", val, expected)
+	}
+
+	if _, err := NormalizeResult([]byte(`{"a":`)); !errors.Is(err, JsonSyntaxError) {
+		t.Errorf("%v != %v", err, JsonSyntaxError)
+	}
+}
+
+type failingWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestResultWriteToError(t *testing.T) {
+	if _, err := Result(`[1]`).WriteTo(failingWriter{}); !errors.Is(err, errWrite) {
+		t.Errorf("%v != %v", err, errWrite)
+	}
+}
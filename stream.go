@@ -0,0 +1,47 @@
+package normalizer
+
+import (
+	"bufio"
+	"io"
+)
+
+// NormalizeStream normalizes a stream of concatenated JSON values using the
+// default settings.
+func NormalizeStream(r io.Reader) ([][]byte, error) {
+	return defaultNormalizer.NormalizeStream(r)
+}
+
+// NormalizeStream normalizes every top-level value read from r until EOF.
+// Values may be separated by filler symbols or directly adjacent, like
+// `{"a":1}{"b":2}`. A number has to be followed by a filler symbol before the
+// next value to tell where it ends.
+func (n *Normalizer) NormalizeStream(r io.Reader) ([][]byte, error) {
+	br, ok := r.(reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	p := n.newInputParser(br)
+	if err := p.skipBOM(); err != nil {
+		return nil, err
+	}
+
+	var values [][]byte
+	for {
+		if err := p.skipFillers(); err != nil {
+			return nil, err
+		}
+		if _, err := p.readByte(); err == io.EOF {
+			return values, nil
+		} else if err != nil {
+			return nil, err
+		}
+		p.unreadByte()
+
+		p.nodes = 0
+		data, err := p.parseTopLevel(nil)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, data)
+	}
+}
@@ -0,0 +1,37 @@
+package normalizer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeStream(t *testing.T) {
+	check := func(src string, expected []string, expectedError error) {
+		values, err := NormalizeStream(strings.NewReader(src))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+			return
+		}
+		if len(values) != len(expected) {
+			t.Errorf("%d values != %d, src: %s", len(values), len(expected), src)
+			return
+		}
+		for i := range values {
+			if string(values[i]) != expected[i] {
+				t.Errorf("%s != %s, src: %s", values[i], expected[i], src)
+			}
+		}
+	}
+
+	check(`{"b": 1, "a": 2} {"c": [3, 4]}`, []string{`{"a":2,"b":1}`, `{"c":[3,4]}`}, nil)
+	check("1\n2\t3 ", []string{`1`, `2`, `3`}, nil)
+	check(`{"a":1}{"b":2}`, []string{`{"a":1}`, `{"b":2}`}, nil)
+	check(`[1][2]"x"null{}`, []string{`[1]`, `[2]`, `"x"`, `null`, `{}`}, nil)
+	check(`truefalse`, []string{`true`, `false`}, nil)
+	check(``, nil, nil)
+	check(`   `, nil, nil)
+
+	check(`{"a":1} {"b":`, nil, ErrUnexpectedEnd)
+	check(`{"a":1} ]`, nil, JsonSyntaxError)
+}
@@ -0,0 +1,176 @@
+package normalizer
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// parseEscape decodes an escape sequence, the leading backslash is already
+// consumed. Surrogate pairs written as two \uXXXX escapes are combined into a
+// single rune, unpaired surrogates are rejected.
+func (p *parser) parseEscape() (rune, error) {
+	c, err := p.readByte()
+	if err != nil {
+		return 0, p.truncated(err)
+	}
+
+	switch c {
+	case '"', '\\', '/':
+		return rune(c), nil
+	case '\'':
+		if !p.singleQuotes {
+			return 0, p.syntaxError()
+		}
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+	default:
+		return 0, p.syntaxError()
+	}
+
+	ch, err := p.parseHex4()
+	if err != nil {
+		return 0, err
+	}
+	if !utf16.IsSurrogate(ch) {
+		return ch, nil
+	}
+	if ch >= 0xdc00 {
+		// low surrogate without a high one
+		return 0, p.syntaxError()
+	}
+
+	for _, expected := range []byte(`\u`) {
+		if c, err := p.readByte(); err != nil {
+			return 0, p.truncated(err)
+		} else if c != expected {
+			return 0, p.syntaxError()
+		}
+	}
+	low, err := p.parseHex4()
+	if err != nil {
+		return 0, err
+	}
+
+	if ch = utf16.DecodeRune(ch, low); ch == utf8.RuneError {
+		return 0, p.syntaxError()
+	}
+	return ch, nil
+}
+
+func (p *parser) parseHex4() (rune, error) {
+	var ch rune
+	for i := 0; i < 4; i++ {
+		c, err := p.readByte()
+		if err != nil {
+			return 0, p.truncated(err)
+		}
+
+		switch {
+		case c >= '0' && c <= '9':
+			ch = ch<<4 | rune(c-'0')
+		case c >= 'a' && c <= 'f':
+			ch = ch<<4 | rune(c-'a'+10)
+		case c >= 'A' && c <= 'F':
+			ch = ch<<4 | rune(c-'A'+10)
+		default:
+			return 0, p.syntaxError()
+		}
+	}
+	return ch, nil
+}
+
+// appendString appends the decoded string s to dst as a quoted JSON string.
+// Only the quote, the backslash and control characters are escaped, using the
+// two-character form where JSON defines one. In ASCII-only mode non-ASCII
+// characters are escaped as well, in HTML-safe mode '<', '>' and '&' are.
+// RFC 8785 allows neither, so they are ignored with JCS.
+func (p *parser) appendString(dst, s []byte) []byte {
+	dst = growBytes(dst, len(s)+2)
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		// copy the run of bytes written as is in one go
+		if j := p.plainRun(s, i); j > i {
+			dst = append(dst, s[i:j]...)
+			if i = j; i == len(s) {
+				break
+			}
+		}
+
+		c := s[i]
+		switch {
+		case c >= utf8.RuneSelf:
+			if !p.escapeNonASCII() {
+				dst = append(dst, c)
+				continue
+			}
+			r, size := utf8.DecodeRune(s[i:])
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				dst = appendUnicodeEscape(dst, r1)
+				dst = appendUnicodeEscape(dst, r2)
+			} else {
+				dst = appendUnicodeEscape(dst, r)
+			}
+			i += size - 1
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\b':
+			dst = append(dst, '\\', 'b')
+		case c == '\f':
+			dst = append(dst, '\\', 'f')
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < 0x20 || p.escapeHTMLChar(c):
+			dst = appendUnicodeEscape(dst, rune(c))
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
+
+// plainRun returns the end of the run of bytes starting at i which
+// appendString copies without escaping.
+func (p *parser) plainRun(s []byte, i int) int {
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == '"' || c == '\\' ||
+			c >= utf8.RuneSelf && p.escapeNonASCII() ||
+			p.escapeHTMLChar(c) {
+			break
+		}
+	}
+	return i
+}
+
+// escapeNonASCII reports whether non-ASCII characters are escaped.
+func (p *parser) escapeNonASCII() bool {
+	return p.asciiOnly && !p.jcs
+}
+
+// escapeHTMLChar reports whether c is escaped in HTML-safe mode.
+func (p *parser) escapeHTMLChar(c byte) bool {
+	return p.escapeHTML && !p.jcs && (c == '<' || c == '>' || c == '&')
+}
+
+// appendUnicodeEscape appends the \uXXXX escape of a BMP code point or a
+// surrogate.
+func appendUnicodeEscape(dst []byte, r rune) []byte {
+	return append(dst, '\\', 'u',
+		hexDigits[r>>12&0xf], hexDigits[r>>8&0xf], hexDigits[r>>4&0xf], hexDigits[r&0xf])
+}
@@ -0,0 +1,157 @@
+package normalizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseStringEscapes(t *testing.T) {
+	check := func(src, expected string, expectedError error) {
+		p := New().newParser(bytes.NewReader([]byte(src)))
+		data, err := p.parseString(nil, '"')
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %s", err, expectedError, src)
+		} else if val := string(data); val != expected {
+			t.Errorf("%v != %v", val, expected)
+		}
+	}
+
+	check(`\u0041"`, `"A"`, nil)
+	check(`A"`, `"A"`, nil)
+	check(`\u00e9t\u00C9"`, `"étÉ"`, nil)
+	check(`\u4e16\u754C"`, `"世界"`, nil)
+	check(`\uD83D\uDE00"`, `"😀"`, nil)
+	check(`\ud83d\ude00 ok"`, `"😀 ok"`, nil)
+	check(`\"\\"`, `"\"\\"`, nil)
+	check(`\/\b\f\n\r\t"`, `"/\b\f\n\r\t"`, nil)
+	check(`\u0000\u001F"`, `"\u0000\u001f"`, nil)
+
+	check(`\uD83D"`, ``, JsonSyntaxError)
+	check(`\uD83Dx"`, ``, JsonSyntaxError)
+	check(`\uD83D\u0041"`, ``, JsonSyntaxError)
+	check(`\uDE00"`, ``, JsonSyntaxError)
+	check(`\u12G4"`, ``, JsonSyntaxError)
+	check(`\x"`, ``, JsonSyntaxError)
+
+	// control characters have to be escaped
+	check("a\tb\"", ``, JsonSyntaxError)
+	check("a\nb\"", ``, JsonSyntaxError)
+	check("\x00\"", ``, JsonSyntaxError)
+	check("\x1f\"", ``, JsonSyntaxError)
+	check("\x7f\"", "\"\x7f\"", nil)
+}
+
+func TestParseStringCanonicalEscapes(t *testing.T) {
+	check := func(expected string, srcs ...string) {
+		for _, src := range srcs {
+			p := New().newParser(bytes.NewReader([]byte(src)))
+			data, err := p.parseString(nil, '"')
+			if err != nil {
+				t.Errorf("%v, src: %s", err, src)
+			} else if val := string(data); val != expected {
+				t.Errorf("%v != %v, src: %s", val, expected, src)
+			}
+		}
+	}
+
+	// characters which do not need escaping
+	check(`"a/b"`, `a/b"`, `a\/b"`, `a\u002fb"`, `a\u002Fb"`)
+	check(`"x'y"`, `x'y"`, `x\u0027y"`)
+	check(`"é"`, `é"`, `\u00e9"`)
+
+	// two-character escapes
+	check(`"\""`, `\""`, `\u0022"`)
+	check(`"\\"`, `\\"`, `\u005c"`, `\u005C"`)
+	check(`"\b"`, `\b"`, `\u0008"`)
+	check(`"\f"`, `\f"`, `\u000c"`, `\u000C"`)
+	check(`"\n"`, `\n"`, `\u000a"`, `\u000A"`)
+	check(`"\r"`, `\r"`, `\u000d"`, `\u000D"`)
+	check(`"\t"`, `\t"`, `\u0009"`)
+
+	// other control characters
+	check(`"\u0000"`, `\u0000"`)
+	check(`"\u001f"`, `\u001f"`, `\u001F"`)
+}
+
+func TestNormalizeUnicodeEscapes(t *testing.T) {
+	check := func(a, b string) {
+		na, err := Normalize([]byte(a))
+		if err != nil {
+			t.Fatalf("%v, src: %s", err, a)
+		}
+		nb, err := Normalize([]byte(b))
+		if err != nil {
+			t.Fatalf("%v, src: %s", err, b)
+		}
+		if !bytes.Equal(na, nb) {
+			t.Errorf("%s != %s", na, nb)
+		}
+	}
+
+	check(`"\u0041"`, `"A"`)
+	check(`{"\u0061": "\uD83D\uDE00"}`, `{"a": "😀"}`)
+	check(`["caf\u00e9"]`, `["café"]`)
+}
+
+func TestUnicodeEscapesLowercase(t *testing.T) {
+	check := func(n *Normalizer, expected string, srcs ...string) {
+		for _, src := range srcs {
+			data, err := n.Normalize([]byte(src))
+			if err != nil {
+				t.Errorf("%v, src: %s", err, src)
+			} else if val := string(data); val != expected {
+				t.Errorf("%v != %v, src: %s", val, expected, src)
+			}
+		}
+	}
+
+	check(New(), `"\u001f\u001b"`, `"\u001f\u001b"`, `"\u001F\u001B"`, `"\u001f\u001B"`)
+	check(New(), `{"\u001b":"\u000b"}`, `{"\u001B": "\u000B"}`, `{"\u001b": "\u000b"}`)
+
+	ascii := New(WithASCIIOnly(true))
+	check(ascii, `"\u00a0\u00e9\ufeff"`, `"\u00A0\u00E9\uFEFF"`, `"\u00a0\u00e9\ufeff"`, "\"\u00a0\u00e9\ufeff\"", `"\u00A0\u00e9\uFeFf"`)
+	check(ascii, `"\ud83d\ude00"`, `"\uD83D\uDE00"`, `"\ud83d\uDE00"`, `"😀"`)
+	check(New(WithEscapeHTML(true)), `"\u003c\u003e\u0026"`, `"\u003C\u003E\u0026"`, `"<>&"`)
+	check(New(WithJCS(true)), `"\u001f"`, `"\u001F"`)
+}
+
+func TestParseStringInPlace(t *testing.T) {
+	// every string is parsed both from a slice, scanning plain runs in place,
+	// and through the byte by byte path
+	srcs := []string{
+		`abc"`, `"`, `a"`, `hello world, this is a longer run"`,
+		`ab\"cd\\ef\/g"`, `caf\u00e9 x"`, `a😀b é c"`, "a\tb\x01c\"", `tail`, `abc\`,
+		"a\xffb\"", `x\uD83D\uDE00y"`, `abc'def"`, "line\nbreak\"",
+	}
+
+	for _, n := range []*Normalizer{New(), New(WithMaxStringLength(8)), New(WithReplaceInvalidUTF8(true))} {
+		for _, src := range srcs {
+			sp := n.newParser(newSliceReader([]byte(src)))
+			expectedData, expectedErr := sp.parseString(nil, '"')
+			bp := n.newParser(bytes.NewReader([]byte(src)))
+			data, err := bp.parseString(nil, '"')
+
+			if !bytes.Equal(data, expectedData) || fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+				t.Errorf("%q, %v != %q, %v, src: %q", data, err, expectedData, expectedErr, src)
+			}
+			if expectedErr == nil && (sp.pos != bp.pos || sp.prev != bp.prev) {
+				t.Errorf("%+v %+v != %+v %+v, src: %q", sp.pos, sp.prev, bp.pos, bp.prev, src)
+			}
+		}
+	}
+
+	sq := New(WithSingleQuotes(true)).newParser(newSliceReader([]byte(`ab"c'd`)))
+	if data, err := sq.parseString(nil, '\''); err != nil || string(data) != `"ab\"c"` {
+		t.Errorf("%s, %v", data, err)
+	} else if c, _ := sq.readByte(); c != 'd' {
+		t.Errorf("%c != d", c)
+	}
+
+	_, err := Normalize([]byte(`{"key": "some value", "other": "more text" x}`))
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Offset != 43 || serr.Column != 44 {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
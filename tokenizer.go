@@ -0,0 +1,209 @@
+package normalizer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// TokenType identifies the kind of a Token.
+type TokenType int
+
+const (
+	ObjectStart TokenType = iota
+	ObjectEnd
+	ArrayStart
+	ArrayEnd
+	Key
+	String
+	Number
+	Bool
+	Null
+)
+
+var tokenTypeNames = [...]string{
+	ObjectStart: "ObjectStart",
+	ObjectEnd:   "ObjectEnd",
+	ArrayStart:  "ArrayStart",
+	ArrayEnd:    "ArrayEnd",
+	Key:         "Key",
+	String:      "String",
+	Number:      "Number",
+	Bool:        "Bool",
+	Null:        "Null",
+}
+
+func (t TokenType) String() string {
+	if t < 0 || int(t) >= len(tokenTypeNames) {
+		return "TokenType(?)"
+	}
+	return tokenTypeNames[t]
+}
+
+// Token is a single element of a JSON document. Value holds the normalized
+// text of keys and scalar values, it is empty for brackets.
+type Token struct {
+	Type  TokenType
+	Value []byte
+}
+
+const (
+	stateValue      = iota // a value is expected
+	stateValueOrEnd        // first value of an array or its end
+	stateKey               // a key is expected
+	stateKeyOrEnd          // first key of an object or its end
+	stateCommaOrEnd        // a separator or the end of the container
+)
+
+// Tokenizer splits a JSON stream into tokens without building the document.
+// Tokens are returned in input order, keys are not sorted. Successive
+// top-level values are allowed, Next returns io.EOF after the last of them.
+type Tokenizer struct {
+	p     *parser
+	stack []byte
+	state int
+}
+
+// NewTokenizer returns a Tokenizer reading from r with the default settings.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return defaultNormalizer.NewTokenizer(r)
+}
+
+// NewTokenizer returns a Tokenizer reading from r.
+func (n *Normalizer) NewTokenizer(r io.Reader) *Tokenizer {
+	br, ok := r.(reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Tokenizer{p: n.newInputParser(br)}
+}
+
+// Next returns the next token. Malformed input is reported as a syntax error,
+// a document cut short as io.ErrUnexpectedEOF.
+func (t *Tokenizer) Next() (Token, error) {
+	p := t.p
+
+	if err := p.skipFillers(); err != nil {
+		return Token{}, err
+	}
+	c, err := p.readByte()
+	if err == io.EOF {
+		if len(t.stack) == 0 {
+			return Token{}, io.EOF
+		}
+		return Token{}, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return Token{}, err
+	}
+
+	switch t.state {
+	case stateCommaOrEnd:
+		if c != ',' {
+			return t.closeContainer(c)
+		}
+		// with trailing commas the container may end after the comma
+		if t.stack[len(t.stack)-1] == '{' {
+			t.state = stateKey
+			if p.trailingCommas {
+				t.state = stateKeyOrEnd
+			}
+		} else {
+			t.state = stateValue
+			if p.trailingCommas {
+				t.state = stateValueOrEnd
+			}
+		}
+		return t.Next()
+
+	case stateKeyOrEnd:
+		if c == '}' {
+			return t.closeContainer(c)
+		}
+		fallthrough
+	case stateKey:
+		if !p.isQuote(c) && !(p.unquotedKeys && isIdentifierStart(c)) {
+			return Token{}, p.syntaxError()
+		}
+		p.unreadByte()
+		key, err := p.parseName()
+		if err != nil {
+			return Token{}, t.unexpectedEOF(err)
+		}
+		t.state = stateValue
+		return Token{Type: Key, Value: p.appendString(nil, []byte(key))}, nil
+
+	case stateValueOrEnd:
+		if c == ']' {
+			return t.closeContainer(c)
+		}
+	}
+
+	switch c {
+	case '{', '[':
+		if err := p.enter(); err != nil {
+			return Token{}, err
+		}
+		t.stack = append(t.stack, c)
+		if c == '{' {
+			t.state = stateKeyOrEnd
+			return Token{Type: ObjectStart}, nil
+		}
+		t.state = stateValueOrEnd
+		return Token{Type: ArrayStart}, nil
+	}
+
+	p.unreadByte()
+	val, err := p.parseValue(nil)
+	if err != nil {
+		return Token{}, t.unexpectedEOF(err)
+	}
+	t.valueDone()
+
+	switch c {
+	case '"', '\'':
+		return Token{Type: String, Value: val}, nil
+	case 'n':
+		return Token{Type: Null, Value: val}, nil
+	case 't', 'f', 'T', 'F':
+		return Token{Type: Bool, Value: val}, nil
+	}
+	if string(val) == "null" {
+		// a non-finite number replaced with null
+		return Token{Type: Null, Value: val}, nil
+	}
+	return Token{Type: Number, Value: val}, nil
+}
+
+func (t *Tokenizer) closeContainer(c byte) (Token, error) {
+	if len(t.stack) == 0 {
+		return Token{}, t.p.syntaxError()
+	}
+
+	top := t.stack[len(t.stack)-1]
+	if !(top == '{' && c == '}') && !(top == '[' && c == ']') {
+		return Token{}, t.p.syntaxError()
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+	t.p.leave()
+	t.valueDone()
+
+	if top == '{' {
+		return Token{Type: ObjectEnd}, nil
+	}
+	return Token{Type: ArrayEnd}, nil
+}
+
+func (t *Tokenizer) valueDone() {
+	if len(t.stack) == 0 {
+		t.state = stateValue
+	} else {
+		t.state = stateCommaOrEnd
+	}
+}
+
+func (t *Tokenizer) unexpectedEOF(err error) error {
+	if err == io.EOF || errors.Is(err, ErrUnexpectedEnd) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
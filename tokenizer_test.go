@@ -0,0 +1,80 @@
+package normalizer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTokenizer(t *testing.T) {
+	check := func(src string, expected []Token, expectedError error) {
+		tok := NewTokenizer(strings.NewReader(src))
+		for i := 0; ; i++ {
+			token, err := tok.Next()
+			if err != nil {
+				if !errors.Is(err, expectedError) {
+					t.Errorf("%v != %v, src: %s", err, expectedError, src)
+				}
+				if i != len(expected) {
+					t.Errorf("%d tokens != %d, src: %s", i, len(expected), src)
+				}
+				return
+			}
+			if i >= len(expected) {
+				t.Errorf("unexpected token %v %s, src: %s", token.Type, token.Value, src)
+				return
+			}
+			if token.Type != expected[i].Type || string(token.Value) != string(expected[i].Value) {
+				t.Errorf("%v %s != %v %s, src: %s", token.Type, token.Value, expected[i].Type, expected[i].Value, src)
+			}
+		}
+	}
+	tok := func(typ TokenType, val string) Token {
+		return Token{Type: typ, Value: []byte(val)}
+	}
+
+	check(`{"b": [1, "x", {"d": null}], "a": {}, "c": [true, false, []], "e": 1.50}`, []Token{
+		tok(ObjectStart, ``),
+		tok(Key, `"b"`),
+		tok(ArrayStart, ``),
+		tok(Number, `1`),
+		tok(String, `"x"`),
+		tok(ObjectStart, ``),
+		tok(Key, `"d"`),
+		tok(Null, `null`),
+		tok(ObjectEnd, ``),
+		tok(ArrayEnd, ``),
+		tok(Key, `"a"`),
+		tok(ObjectStart, ``),
+		tok(ObjectEnd, ``),
+		tok(Key, `"c"`),
+		tok(ArrayStart, ``),
+		tok(Bool, `true`),
+		tok(Bool, `false`),
+		tok(ArrayStart, ``),
+		tok(ArrayEnd, ``),
+		tok(ArrayEnd, ``),
+		tok(Key, `"e"`),
+		tok(Number, `1.5`),
+		tok(ObjectEnd, ``),
+	}, io.EOF)
+
+	check(`1 "a" []`, []Token{
+		tok(Number, `1`),
+		tok(String, `"a"`),
+		tok(ArrayStart, ``),
+		tok(ArrayEnd, ``),
+	}, io.EOF)
+	check(``, nil, io.EOF)
+
+	check(`[1, 2`, []Token{tok(ArrayStart, ``), tok(Number, `1`), tok(Number, `2`)}, io.ErrUnexpectedEOF)
+	check(`{"a"`, []Token{tok(ObjectStart, ``)}, io.ErrUnexpectedEOF)
+	check(`["ab`, []Token{tok(ArrayStart, ``)}, io.ErrUnexpectedEOF)
+	check(`[tr`, []Token{tok(ArrayStart, ``)}, io.ErrUnexpectedEOF)
+	check(`[1}`, []Token{tok(ArrayStart, ``), tok(Number, `1`)}, JsonSyntaxError)
+	check(`{1: 2}`, []Token{tok(ObjectStart, ``)}, JsonSyntaxError)
+	check(`[1 2]`, []Token{tok(ArrayStart, ``), tok(Number, `1`)}, JsonSyntaxError)
+	check(`[,]`, []Token{tok(ArrayStart, ``)}, JsonSyntaxError)
+	check(`]`, nil, JsonSyntaxError)
+}
@@ -0,0 +1,93 @@
+package normalizer
+
+import (
+	"errors"
+)
+
+// Validate reports the errors in src using the default settings.
+func Validate(src []byte) []error {
+	return defaultNormalizer.Validate(src)
+}
+
+// Validate checks src like Valid, but instead of stopping at the first
+// syntax error it tries to recover from it and reports every error found, in
+// input order, or nil if src is valid.
+//
+// Recovery works at the boundaries of object members and array elements:
+// after an error the input is skipped up to the next comma or closing bracket
+// at the same nesting level, outside of strings, and parsing resumes with the
+// next member or the end of the container. A closing bracket of the wrong
+// kind ends the container as well. Duplicate keys rejected by
+// WithDuplicateKeys are reported once the object is complete. Errors outside
+// of any container, at the end of the input or other than syntax errors, like
+// exceeded limits, stop validation. The first error is the one returned by
+// Normalize, the following ones may be caused by an unfortunate recovery from
+// the earlier ones.
+func (n *Normalizer) Validate(src []byte) []error {
+	p := n.newInputParser(newSliceReader(src))
+	p.discard = true
+	p.collect = true
+	if _, err := p.parseDocument(); err != nil {
+		p.errs = append(p.errs, err)
+	}
+	return p.errs
+}
+
+// recoverMember handles the error err met in a member or an element of the
+// container being parsed. When collecting errors, syntax errors are recorded
+// and the input is skipped to the next comma or closing bracket, more then
+// reports whether the container has another member. Any other error is
+// returned as is.
+func (p *parser) recoverMember(err error) (more bool, _ error) {
+	var serr *SyntaxError
+	if !p.collect || !errors.As(err, &serr) || serr.Err == ErrUnexpectedEnd {
+		return false, err
+	}
+	p.errs = append(p.errs, err)
+
+	// the offending byte may be the comma or bracket looked for
+	if serr.Offset == p.pos.offset-1 {
+		p.unreadByte()
+	}
+	quote := p.inString
+	p.inString = 0
+
+	depth := 0
+	for {
+		c, err := p.readByte()
+		if err != nil {
+			return false, p.truncated(err)
+		}
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				if _, err := p.readByte(); err != nil {
+					return false, p.truncated(err)
+				}
+			} else if c == quote {
+				quote = 0
+			}
+		case p.isQuote(c):
+			quote = c
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			if depth == 0 {
+				return false, nil
+			}
+			depth--
+		case c == ',' && depth == 0:
+			return true, nil
+		}
+	}
+}
+
+// recordDuplicate records the duplicate key error err when collecting errors,
+// it is returned otherwise.
+func (p *parser) recordDuplicate(err error) error {
+	if !p.collect || !errors.Is(err, ErrDuplicateKey) {
+		return err
+	}
+	p.errs = append(p.errs, err)
+	return nil
+}
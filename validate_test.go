@@ -0,0 +1,87 @@
+package normalizer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type expectedError struct {
+		err    error
+		offset int64
+		path   string
+	}
+	check := func(n *Normalizer, src string, expected ...expectedError) {
+		errs := n.Validate([]byte(src))
+		if len(errs) != len(expected) {
+			t.Errorf("%d errors != %d: %v, src: %s", len(errs), len(expected), errs, src)
+			return
+		}
+		for i, err := range errs {
+			if !errors.Is(err, expected[i].err) {
+				t.Errorf("%v != %v, src: %s", err, expected[i].err, src)
+			}
+			var serr *SyntaxError
+			if errors.As(err, &serr) && (serr.Offset != expected[i].offset || serr.Path != expected[i].path) {
+				t.Errorf("%d %q != %d %q, src: %s", serr.Offset, serr.Path, expected[i].offset, expected[i].path, src)
+			}
+		}
+	}
+
+	n := New()
+	check(n, `{"b": [1, 2], "a": {"c": "x"}}`)
+	check(n, `[]`)
+
+	// two independent errors
+	check(n, `{"a": [1, x, 3], "b": {"c": tru, "d": 4}, "e": 5}`,
+		expectedError{JsonSyntaxError, 10, "a[1]"},
+		expectedError{JsonSyntaxError, 31, "b.c"})
+
+	// a closing bracket of the wrong kind ends the container
+	check(n, `[1 2, "a\"b", 3}, 4]`,
+		expectedError{JsonSyntaxError, 3, ""},
+		expectedError{JsonSyntaxError, 15, ""},
+		expectedError{ErrTrailingData, 16, ""})
+
+	// strings are skipped, also when the error is inside one
+	check(n, `["x\y, ]", "z", 1.2.3, [4, {"a": "b, ]"}]]`,
+		expectedError{JsonSyntaxError, 4, "[0]"},
+		expectedError{JsonSyntaxError, 19, "[2]"})
+	check(n, `{"a": [1, {"b": 2 "c"}], "d" 5, "e": 6}`,
+		expectedError{JsonSyntaxError, 18, "a[1]"},
+		expectedError{JsonSyntaxError, 29, ""})
+	check(n, `{"a": 1, "a" "b": 2, 3: 4}`,
+		expectedError{JsonSyntaxError, 13, ""},
+		expectedError{JsonSyntaxError, 21, ""})
+	check(n, `[1, , 2]`, expectedError{JsonSyntaxError, 4, "[1]"})
+	check(n, `[1, 2,]`, expectedError{JsonSyntaxError, 6, "[2]"})
+
+	// some errors can't be recovered from
+	check(n, `[1, x, [2`,
+		expectedError{JsonSyntaxError, 4, "[1]"},
+		expectedError{ErrUnexpectedEnd, 9, "[2]"})
+	check(n, `[1, "x`, expectedError{ErrUnexpectedEnd, 6, "[1]"})
+	check(n, `x`, expectedError{JsonSyntaxError, 0, ""})
+	check(n, `[x] 1`,
+		expectedError{JsonSyntaxError, 1, "[0]"},
+		expectedError{ErrTrailingData, 4, ""})
+	check(n, ``, expectedError{ErrEmptyInput, 0, ""})
+	check(New(WithMaxDepth(2)), `[x, [[1]], y]`,
+		expectedError{JsonSyntaxError, 1, "[0]"},
+		expectedError{ErrMaxDepthExceeded, 0, ""})
+
+	// duplicate keys are reported with the object
+	reject := New(WithRejectDuplicateKeys(true))
+	check(reject, `[{"a": 1, "a": 2}, x, {"b": [y], "b": 3}]`,
+		expectedError{ErrDuplicateKey, 0, ""},
+		expectedError{JsonSyntaxError, 19, "[1]"},
+		expectedError{JsonSyntaxError, 29, "[2].b[0]"},
+		expectedError{ErrDuplicateKey, 0, ""})
+
+	// the first error is the one of Normalize
+	src := `{"a": [1, x, 3], "b": tru}`
+	_, err := Normalize([]byte(src))
+	if errs := Validate([]byte(src)); len(errs) != 2 || errs[0].Error() != err.Error() {
+		t.Errorf("%v, %v", errs, err)
+	}
+}
@@ -0,0 +1,116 @@
+package normalizer
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// ParseValue reads a single JSON value from r and returns it normalized using
+// the default settings.
+func ParseValue(r io.Reader) ([]byte, error) {
+	return defaultNormalizer.ParseValue(r)
+}
+
+// ParseValue reads a single JSON value from r, skipping the filler symbols in
+// front of it, and returns it normalized. Nothing past the value is consumed,
+// so that r can be used to read whatever follows it, like another value. When
+// r holds nothing but filler symbols io.EOF is returned.
+//
+// Readers implementing io.ByteScanner and io.RuneReader, like *bytes.Reader,
+// *strings.Reader and *bufio.Reader, are read directly. Other readers are
+// read one byte at a time; as their reads can't be undone, a top-level
+// number, which only ends at the byte following it, consumes that byte too.
+// Wrap them in a *bufio.Reader when reading ahead does not matter.
+func (n *Normalizer) ParseValue(r io.Reader) ([]byte, error) {
+	br, ok := r.(reader)
+	if !ok {
+		br = newByteReader(r)
+	}
+	p := n.newInputParser(br)
+
+	if err := p.skipFillers(); err != nil {
+		return nil, err
+	}
+	if _, err := p.readByte(); err != nil {
+		return nil, err
+	}
+	p.unreadByte()
+
+	return p.parseTopLevel(nil)
+}
+
+// byteReader implements reader on top of an io.Reader without reading ahead,
+// only the bytes of an incomplete rune are kept.
+type byteReader struct {
+	r       io.Reader
+	pending [utf8.UTFMax]byte
+	n       int // number of pending bytes
+	last    int // last byte returned by ReadByte, -1 if it can't be unread
+
+	// err is the error returned by r, reported once the pending bytes are
+	// consumed
+	err error
+}
+
+func newByteReader(r io.Reader) *byteReader {
+	return &byteReader{r: r, last: -1}
+}
+
+// fill reads until at least n bytes are pending.
+func (b *byteReader) fill(n int) error {
+	for b.n < n {
+		if b.err != nil {
+			return b.err
+		}
+		m, err := b.r.Read(b.pending[b.n:n])
+		b.n += m
+		b.err = err
+	}
+	return nil
+}
+
+// consume drops the first size pending bytes.
+func (b *byteReader) consume(size int) {
+	copy(b.pending[:], b.pending[size:b.n])
+	b.n -= size
+}
+
+func (b *byteReader) ReadByte() (byte, error) {
+	b.last = -1
+	if err := b.fill(1); err != nil {
+		return 0, err
+	}
+	c := b.pending[0]
+	b.consume(1)
+	b.last = int(c)
+	return c, nil
+}
+
+func (b *byteReader) UnreadByte() error {
+	if b.last < 0 {
+		return bufio.ErrInvalidUnreadByte
+	}
+	copy(b.pending[1:], b.pending[:b.n])
+	b.pending[0] = byte(b.last)
+	b.n++
+	b.last = -1
+	return nil
+}
+
+func (b *byteReader) ReadRune() (rune, int, error) {
+	b.last = -1
+	if err := b.fill(1); err != nil {
+		return 0, 0, err
+	}
+	for b.n < utf8.UTFMax && !utf8.FullRune(b.pending[:b.n]) {
+		if err := b.fill(b.n + 1); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, 0, err
+		}
+	}
+	ch, size := utf8.DecodeRune(b.pending[:b.n])
+	b.consume(size)
+	return ch, size, nil
+}
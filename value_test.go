@@ -0,0 +1,107 @@
+package normalizer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseValueSequence(t *testing.T) {
+	const src = ` {"b": 1, "a": [1.50, "é😀"]} 12 "x"[true,null]-0 tail`
+	expected := []string{`{"a":[1.5,"é😀"],"b":1}`, `12`, `"x"`, `[true,null]`, `0`}
+
+	check := func(name string, r io.Reader, rest string) {
+		for _, exp := range expected {
+			data, err := ParseValue(r)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				return
+			} else if val := string(data); val != exp {
+				t.Errorf("%s: %v != %v", name, val, exp)
+			}
+		}
+		if val, err := io.ReadAll(r); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if string(val) != rest {
+			t.Errorf("%s: unexpected rest %q", name, val)
+		}
+	}
+
+	check("bytes", bytes.NewReader([]byte(src)), " tail")
+	check("strings", strings.NewReader(src), " tail")
+	check("bufio", bufio.NewReader(strings.NewReader(src)), " tail")
+	// the space ending -0 can't be put back
+	check("plain", io.MultiReader(strings.NewReader(src)), "tail")
+	check("one byte", iotest.OneByteReader(strings.NewReader(src)), "tail")
+	check("half", iotest.HalfReader(strings.NewReader(src)), "tail")
+
+	// only the byte ending a top-level number is consumed
+	r := iotest.OneByteReader(strings.NewReader(`{"a":1},[2]]`))
+	for _, exp := range []string{`{"a":1}`, `,`, `[2]`, `]`} {
+		if len(exp) == 1 {
+			var buf [1]byte
+			if _, err := io.ReadFull(r, buf[:]); err != nil || string(buf[:]) != exp {
+				t.Errorf("%q != %q, %v", buf, exp, err)
+			}
+		} else if data, err := ParseValue(r); err != nil || string(data) != exp {
+			t.Errorf("%s != %s, %v", data, exp, err)
+		}
+	}
+}
+
+func TestParseValueErrors(t *testing.T) {
+	check := func(src string, expectedError error) {
+		_, err := ParseValue(iotest.OneByteReader(strings.NewReader(src)))
+		if !errors.Is(err, expectedError) {
+			t.Errorf("%v != %v, src: %q", err, expectedError, src)
+		}
+	}
+
+	check(``, io.EOF)
+	check(" \n\t", io.EOF)
+	check(`{"a" 1}`, JsonSyntaxError)
+	check("\"\xe9\"", ErrInvalidUTF8)
+	check(`x`, JsonSyntaxError)
+
+	errRead := errors.New("read failed")
+	if _, err := ParseValue(iotest.ErrReader(errRead)); !errors.Is(err, errRead) {
+		t.Errorf("%v != %v", err, errRead)
+	}
+}
+
+func TestByteReader(t *testing.T) {
+	r := newByteReader(iotest.OneByteReader(strings.NewReader("a\xe9b😀")))
+
+	if err := r.UnreadByte(); err == nil {
+		t.Error("unread before a read")
+	}
+	if c, err := r.ReadByte(); err != nil || c != 'a' {
+		t.Errorf("%q, %v", c, err)
+	}
+	if err := r.UnreadByte(); err != nil {
+		t.Error(err)
+	}
+	if err := r.UnreadByte(); err == nil {
+		t.Error("second unread")
+	}
+	if ch, size, err := r.ReadRune(); err != nil || ch != 'a' || size != 1 {
+		t.Errorf("%q %d, %v", ch, size, err)
+	}
+	// the invalid byte is returned on its own, the following one is kept
+	if ch, size, err := r.ReadRune(); err != nil || ch != '�' || size != 1 {
+		t.Errorf("%q %d, %v", ch, size, err)
+	}
+	if c, err := r.ReadByte(); err != nil || c != 'b' {
+		t.Errorf("%q, %v", c, err)
+	}
+	if ch, size, err := r.ReadRune(); err != nil || ch != '😀' || size != 4 {
+		t.Errorf("%q %d, %v", ch, size, err)
+	}
+	if _, _, err := r.ReadRune(); err != io.EOF {
+		t.Errorf("%v != %v", err, io.EOF)
+	}
+}